@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/plugins/tools"
+	"github.com/spf13/cobra"
+)
+
+// askCmd answers a single question and exits.
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Answer a single question and exit",
+	Long: `Answer a single question, calling the tools if needed, print the answer and exit.
+
+When the standard input is piped, it is sent along with the question as context:
+
+  git diff | pishia ask "write a commit message"
+
+With --output ndjson, the events of the answer are printed as JSON objects, one per line: token,
+tool_call_started, tool_call_finished, usage, error and message. With --output json, a single JSON
+object is printed when the answer is done. The logs are always printed to the standard error.
+
+The exit status is 0 on success, 2 for invalid arguments, 3 for an invalid configuration,
+4 when the assistant can't be set up and 5 when the assistant fails to answer.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		core.SetSessionID(core.NewSessionID())
+
+		question := ""
+		if len(args) > 0 {
+			question = strings.TrimSpace(args[0])
+		}
+
+		input, err := readPipedInput(cmd.InOrStdin())
+		if err != nil {
+			return withExitCode(exitFailure, "reading the standard input: %w", err)
+		}
+
+		// The error is printed when the command exits.
+		output, err := newCommandOutput(cmd, nil)
+		if err != nil {
+			return withExitCode(exitUsage, "%w", err)
+		}
+
+		prompt := askPrompt(question, input)
+		if prompt == "" {
+			return withExitCode(exitUsage, "no question given, pass it as an argument or pipe it")
+		}
+
+		err = core.BootWith(func(cfg *config.Base) {
+			applyAskFlags(cmd, cfg)
+		})
+		if err != nil {
+			return withExitCode(exitConfig, "booting the core: %w", err)
+		}
+
+		noTools, _ := cmd.Flags().GetBool("no-tools")
+		if noTools {
+			tools.SetRepository(tools.NewToolRepository())
+		}
+
+		assistant := assistants.GetDefaultAssistant()
+		if assistant == nil {
+			return withExitCode(exitConfig, "booting the core: no assistant available")
+		}
+
+		// The handler is set first to show the progress of the model pull, if any.
+		assistant.SetEventHandler(output.Handle)
+
+		err = assistant.Setup(cmd.Context())
+		if err != nil {
+			return withExitCode(exitUnavailable, "setting up the assistant: %w", err)
+		}
+
+		// Ctrl-C stops the answer.
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		output.Start()
+		err = assistant.SendRequest(ctx, prompt, output.Callback)
+		if err != nil {
+			output.Fail(err)
+		}
+
+		err = output.Finish()
+		if err != nil {
+			return withExitCode(exitRequest, "sending the request: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// applyAskFlags overrides the configuration with the --plugin, --model and --system flags, and with the
+// flags of the generation.
+func applyAskFlags(cmd *cobra.Command, cfg *config.Base) {
+	if plugin, _ := cmd.Flags().GetString("plugin"); plugin != "" {
+		cfg.Assistants.Plugin = plugin
+	}
+
+	if model, _ := cmd.Flags().GetString("model"); model != "" {
+		switch cfg.Assistants.Plugin {
+		case "ollama":
+			cfg.Assistants.Ollama.Model = model
+		case "openai":
+			cfg.Assistants.OpenAI.Model = model
+		}
+	}
+
+	if system, _ := cmd.Flags().GetString("system"); system != "" {
+		cfg.Prompts.System = system
+	}
+
+	applyGenerationFlags(cmd, &cfg.Assistants.Generation)
+}
+
+// applyGenerationFlags overrides the parameters of the generation with the flags set on the command.
+func applyGenerationFlags(cmd *cobra.Command, generation *config.Generation) {
+	flags := cmd.Flags()
+
+	if flags.Changed("temperature") {
+		temperature, _ := flags.GetFloat64("temperature")
+		generation.Temperature = &temperature
+	}
+
+	if flags.Changed("top-p") {
+		topP, _ := flags.GetFloat64("top-p")
+		generation.TopP = &topP
+	}
+
+	if flags.Changed("max-tokens") {
+		generation.MaxTokens, _ = flags.GetInt("max-tokens")
+	}
+
+	if flags.Changed("num-ctx") {
+		generation.NumCtx, _ = flags.GetInt("num-ctx")
+	}
+
+	if flags.Changed("seed") {
+		seed, _ := flags.GetInt("seed")
+		generation.Seed = &seed
+	}
+
+	if flags.Changed("stop") {
+		generation.Stop, _ = flags.GetStringArray("stop")
+	}
+
+	if flags.Changed("repeat-penalty") {
+		repeatPenalty, _ := flags.GetFloat64("repeat-penalty")
+		generation.RepeatPenalty = &repeatPenalty
+	}
+
+	if flags.Changed("format") {
+		generation.Format, _ = flags.GetString("format")
+	}
+}
+
+// readPipedInput reads the standard input when it is piped or redirected, it is empty for a terminal.
+func readPipedInput(in io.Reader) (string, error) {
+	if file, ok := in.(*os.File); ok {
+		info, err := file.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return "", nil
+		}
+	}
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// askPrompt combines the question with the piped input.
+func askPrompt(question string, input string) string {
+	switch {
+	case input == "":
+		return question
+	case question == "":
+		return input
+	}
+
+	return fmt.Sprintf("%s\n\n%s", question, input)
+}
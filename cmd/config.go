@@ -0,0 +1,494 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd groups the commands to manage the configuration.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the Pishia configuration",
+	Long:  `Manage the Pishia configuration file: show it, read and change keys, validate it or create it interactively.`,
+}
+
+// configPathCmd prints the path of the configuration file.
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the configuration file",
+	Long: `Print the path of the configuration file changed by the config commands. With --all, print every
+configuration file in the order they are applied, and the data, cache and state directories.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+
+		all, _ := cmd.Flags().GetBool("all")
+		if !all {
+			fmt.Fprintln(out, config.ConfigPath())
+			return
+		}
+
+		files := config.ConfigFiles()
+		layers := []struct{ name, path string }{
+			{"system", config.SystemConfigPath()},
+			{"user", config.UserConfigPath()},
+			{"project", config.ProjectConfigPath()},
+			{"flag", flagConfigPath(cmd)},
+		}
+
+		for _, layer := range layers {
+			if layer.path == "" {
+				continue
+			}
+
+			status := "missing"
+			if _, err := os.Stat(layer.path); err == nil && slices.Contains(files, layer.path) {
+				status = "loaded"
+			}
+
+			fmt.Fprintf(out, "%-8s %s (%s)\n", layer.name, layer.path, status)
+		}
+
+		fmt.Fprintf(out, "%-8s %s\n", "data", config.DataDir())
+		fmt.Fprintf(out, "%-8s %s\n", "cache", config.CacheDir())
+		fmt.Fprintf(out, "%-8s %s\n", "state", config.StateDir())
+	},
+}
+
+// configShowCmd prints the effective configuration.
+var configShowCmd = &cobra.Command{
+	Use:          "show",
+	Short:        "Print the effective configuration, with the secrets masked",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.GetCurrentConfigurations()
+		if err != nil {
+			return err
+		}
+
+		output, err := yaml.Marshal(config.MaskSecrets(cfg))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), string(output))
+		return nil
+	},
+}
+
+// configGetCmd prints the effective value of a key.
+var configGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "Print the effective value of a key, like assistants.ollama.model",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+
+		if !config.KnownKey(key) {
+			return fmt.Errorf("unknown key %q", key)
+		}
+
+		cfg, err := config.GetCurrentConfigurations()
+		if err != nil {
+			return err
+		}
+
+		reveal, _ := cmd.Flags().GetBool("reveal")
+		if !reveal {
+			cfg = config.MaskSecrets(cfg)
+		}
+
+		var document yaml.Node
+
+		err = document.Encode(cfg)
+		if err != nil {
+			return err
+		}
+
+		node, ok := config.GetNode(&document, key)
+		if !ok {
+			return fmt.Errorf("key %q is not set", key)
+		}
+
+		if node.Kind == yaml.ScalarNode {
+			value := node.Value
+
+			// The secrets are only resolved when they are revealed.
+			if reveal && config.IsSecretKey(key) {
+				if value, err = config.ResolveSecret(value); err != nil {
+					return err
+				}
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), value)
+			return nil
+		}
+
+		output, err := yaml.Marshal(node)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), string(output))
+		return nil
+	},
+}
+
+// configSetCmd changes the value of a key in the configuration file.
+var configSetCmd = &cobra.Command{
+	Use:          "set <key> <value>",
+	Short:        "Change the value of a key in the configuration file, keeping its comments",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+
+		if !config.DoesConfigExist() {
+			err := config.CreateDefaultConfig()
+			if err != nil {
+				return err
+			}
+		}
+
+		document, err := config.ReadDocument(config.ConfigPath())
+		if err != nil {
+			return err
+		}
+
+		// The file is rewritten anyway, it is migrated along with the change.
+		applied, err := config.Migrate(document)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range applied {
+			cmd.PrintErrf("Configuration migrated from version %d: %s\n", migration.From, migration.Description)
+		}
+
+		err = config.SetValue(document, key, value)
+		if err != nil {
+			return err
+		}
+
+		data, err := config.EncodeDocument(document)
+		if err != nil {
+			return err
+		}
+
+		// Reject the change if the new value itself is invalid, other problems are only reported.
+		var written yaml.Node
+
+		err = yaml.Unmarshal(data, &written)
+		if err != nil {
+			return err
+		}
+
+		line := 0
+		if node, ok := config.GetNode(&written, key); ok {
+			line = node.Line
+		}
+
+		problems := validate(data)
+		warnings := make([]config.Problem, 0, len(problems))
+
+		for _, problem := range problems {
+			if problem.Path == key || strings.HasPrefix(problem.Path, key+".") || (line > 0 && problem.Line == line) {
+				return &config.ValidationError{Problems: []config.Problem{problem}}
+			}
+			warnings = append(warnings, problem)
+		}
+
+		err = config.WriteDocument(config.ConfigPath(), document)
+		if err != nil {
+			return err
+		}
+
+		if len(warnings) > 0 {
+			cmd.PrintErrln((&config.ValidationError{Problems: warnings}).Error())
+		}
+
+		return nil
+	},
+}
+
+// configValidateCmd validates the configuration file.
+var configValidateCmd = &cobra.Command{
+	Use:          "validate",
+	Short:        "Validate the configuration file",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(config.ConfigPath())
+		if err != nil {
+			return err
+		}
+
+		problems := validate(data)
+		if len(problems) > 0 {
+			return &config.ValidationError{Problems: problems}
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", config.ConfigPath())
+		return nil
+	},
+}
+
+// configEditCmd opens the configuration file in the editor of the user.
+var configEditCmd = &cobra.Command{
+	Use:          "edit",
+	Short:        "Open the configuration file in $VISUAL or $EDITOR and validate it",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !config.DoesConfigExist() {
+			err := config.CreateDefaultConfig()
+			if err != nil {
+				return err
+			}
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+			if runtime.GOOS == "windows" {
+				editor = "notepad"
+			}
+		}
+
+		// The editor may come with arguments, like "code --wait".
+		fields := strings.Fields(editor)
+		editorCmd := exec.Command(fields[0], append(fields[1:], config.ConfigPath())...)
+		editorCmd.Stdin = os.Stdin
+		editorCmd.Stdout = os.Stdout
+		editorCmd.Stderr = os.Stderr
+
+		err := editorCmd.Run()
+		if err != nil {
+			return fmt.Errorf("running the editor %q: %w", editor, err)
+		}
+
+		data, err := os.ReadFile(config.ConfigPath())
+		if err != nil {
+			return err
+		}
+
+		problems := validate(data)
+		if len(problems) > 0 {
+			return &config.ValidationError{Problems: problems}
+		}
+
+		return nil
+	},
+}
+
+// configInitCmd creates the configuration file interactively.
+var configInitCmd = &cobra.Command{
+	Use:          "init",
+	Short:        "Create the configuration file interactively",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reader := bufio.NewReader(cmd.InOrStdin())
+		out := cmd.OutOrStdout()
+
+		force, _ := cmd.Flags().GetBool("force")
+		if config.DoesConfigExist() && !force {
+			answer, err := ask(reader, out, fmt.Sprintf("%s already exists, overwrite it?", config.ConfigPath()), "n")
+			if err != nil {
+				return err
+			}
+
+			if !strings.HasPrefix(strings.ToLower(answer), "y") {
+				return nil
+			}
+		}
+
+		cfg := config.DefaultConfig()
+
+		plugin, err := askChoice(reader, out, "Assistant plugin", assistants.Plugins(), cfg.Assistants.Plugin)
+		if err != nil {
+			return err
+		}
+		cfg.Assistants.Plugin = plugin
+
+		switch plugin {
+		case "ollama":
+			if cfg.Assistants.Ollama.Model, err = ask(reader, out, "Model", cfg.Assistants.Ollama.Model); err != nil {
+				return err
+			}
+			if cfg.Assistants.Ollama.Endpoint, err = ask(reader, out, "Endpoint", cfg.Assistants.Ollama.Endpoint); err != nil {
+				return err
+			}
+		case "openai":
+			if cfg.Assistants.OpenAI.Model, err = ask(reader, out, "Model", cfg.Assistants.OpenAI.Model); err != nil {
+				return err
+			}
+			if cfg.Assistants.OpenAI.Endpoint, err = ask(reader, out, "Endpoint", cfg.Assistants.OpenAI.Endpoint); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "The API key can be a reference instead of the key itself: ${OPENAI_API_KEY}, file:~/.openai-key or cmd:pass show openai")
+			if cfg.Assistants.OpenAI.APIKey, err = ask(reader, out, "API key", "${OPENAI_API_KEY}"); err != nil {
+				return err
+			}
+		}
+
+		err = config.WriteConfig(cfg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Configuration written to %s\n", config.ConfigPath())
+		return nil
+	},
+}
+
+// configProfilesCmd lists the profiles.
+var configProfilesCmd = &cobra.Command{
+	Use:          "profiles",
+	Short:        "List the configuration profiles, the active one is marked with *",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.GetCurrentConfigurations()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range cfg.ProfileNames() {
+			marker := " "
+			if name == cfg.ActiveProfile() {
+				marker = "*"
+			}
+
+			line := fmt.Sprintf("%s %s", marker, name)
+			if inherits := cfg.Profiles[name].Inherits; inherits != "" {
+				line += fmt.Sprintf(" (inherits %s)", inherits)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+		}
+
+		return nil
+	},
+}
+
+// configMigrateCmd migrates the configuration file to the current schema version.
+var configMigrateCmd = &cobra.Command{
+	Use:          "migrate",
+	Short:        "Migrate the configuration file to the current schema version",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		applied, migrated, backup, err := config.MigrateFile(config.ConfigPath(), dryRun)
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Fprintf(out, "%s is already at version %d\n", config.ConfigPath(), config.CurrentVersion)
+			return nil
+		}
+
+		for _, migration := range applied {
+			fmt.Fprintf(out, "version %d -> %d: %s\n", migration.From, migration.From+1, migration.Description)
+		}
+
+		if dryRun {
+			fmt.Fprintln(out, "\nMigrated configuration (not written):")
+			fmt.Fprint(out, string(migrated))
+			return nil
+		}
+
+		fmt.Fprintf(out, "%s migrated to version %d, backup saved to %s\n", config.ConfigPath(), config.CurrentVersion, backup)
+		return nil
+	},
+}
+
+// validate parses and validates a configuration file along with the other configuration files,
+// returning all their problems.
+func validate(data []byte) []config.Problem {
+	cfg, err := config.ParseWith(config.ConfigPath(), data)
+	if err == nil {
+		err = config.Validate(cfg, core.Names())
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Problems
+	}
+
+	return []config.Problem{{File: config.ConfigPath(), Message: err.Error()}}
+}
+
+// flagConfigPath returns the configuration file given with --config.
+func flagConfigPath(cmd *cobra.Command) string {
+	path, _ := cmd.Flags().GetString("config")
+	return path
+}
+
+// ask asks a question, returning the default value if the answer is empty.
+func ask(reader *bufio.Reader, out io.Writer, question string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(out, "%s: ", question)
+	}
+
+	answer, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		if err == io.EOF {
+			return defaultValue, nil
+		}
+		return "", err
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue, nil
+	}
+
+	return answer, nil
+}
+
+// askChoice asks a question until the answer is one of the choices.
+func askChoice(reader *bufio.Reader, out io.Writer, question string, choices []string, defaultValue string) (string, error) {
+	for {
+		answer, err := ask(reader, out, fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", ")), defaultValue)
+		if err != nil {
+			return "", err
+		}
+
+		for _, choice := range choices {
+			if answer == choice {
+				return answer, nil
+			}
+		}
+
+		fmt.Fprintf(out, "Unknown choice %q\n", answer)
+	}
+}
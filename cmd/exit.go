@@ -0,0 +1,39 @@
+package cmd
+
+import "fmt"
+
+const (
+	// exitFailure is the status of the errors without a more specific status.
+	exitFailure = 1
+	// exitUsage is the status of invalid arguments.
+	exitUsage = 2
+	// exitConfig is the status of an invalid configuration.
+	exitConfig = 3
+	// exitUnavailable is the status of an assistant that can't be set up, like an unreachable backend.
+	exitUnavailable = 4
+	// exitRequest is the status of a request the assistant failed to answer.
+	exitRequest = 5
+)
+
+// exitError is an error that sets the exit status of Pishia.
+type exitError struct {
+	// code is the exit status.
+	code int
+	// err is the error.
+	err error
+}
+
+// Error returns the message of the error.
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode wraps the error to exit with the code.
+func withExitCode(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
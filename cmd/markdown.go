@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	"golang.org/x/term"
+)
+
+const (
+	// renderAuto renders the Markdown when the standard output is a terminal.
+	renderAuto = "auto"
+	// renderAlways always renders the Markdown.
+	renderAlways = "always"
+	// renderNever never renders the Markdown, it is printed as it is.
+	renderNever = "never"
+	// defaultWidth is the width used when the width of the terminal is unknown.
+	defaultWidth = 80
+	// codeStyle is the chroma style of the code blocks.
+	codeStyle = "monokai"
+)
+
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiStrike    = "\x1b[9m"
+	ansiCyan      = "\x1b[36m"
+	ansiHeading   = "\x1b[1;35m"
+)
+
+var (
+	// headingLine matches the headings, like ## Title.
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	// listLine matches the items of the lists, like - item or 1. item.
+	listLine = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	// ruleLine matches the horizontal rules.
+	ruleLine = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	// quoteLine matches the block quotes.
+	quoteLine = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	// fenceLine matches the start and the end of the code blocks.
+	fenceLine = regexp.MustCompile("^\\s*(```|~~~)\\s*([\\w+#.-]*)")
+	// tableSeparator matches the line separating the header of a table from its rows.
+	tableSeparator = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*\|?$`)
+	// boldText, italicText, strikeText and linkText match the inline styles.
+	boldText   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicText = regexp.MustCompile(`(^|[^\w*])\*([^*\s][^*]*)\*|(^|[^\w_])_([^_\s][^_]*)_`)
+	strikeText = regexp.MustCompile(`~~([^~]+)~~`)
+	linkText   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	// ansiCode matches the ANSI escape codes, to measure the visible width of a text.
+	ansiCode = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// markdownRenderer renders streamed Markdown for a terminal. The text is rendered line by line as it is
+// received, the code blocks and the tables are rendered when they end.
+type markdownRenderer struct {
+	// out is where the rendered text is written.
+	out io.Writer
+	// width is the width of the terminal, the paragraphs are wrapped to it.
+	width int
+	// pending is the start of the line being received.
+	pending bytes.Buffer
+	// inCode checks if the lines are in a code block.
+	inCode bool
+	// fence is the delimiter of the current code block.
+	fence string
+	// language is the language of the current code block.
+	language string
+	// code are the lines of the current code block.
+	code []string
+	// table are the lines of the current table.
+	table []string
+}
+
+// newMarkdownRenderer creates a markdownRenderer for a terminal of the given width.
+func newMarkdownRenderer(out io.Writer, width int) *markdownRenderer {
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	return &markdownRenderer{
+		out:   out,
+		width: width,
+	}
+}
+
+// shouldRender checks if the Markdown written to out is rendered for the --render mode, in auto mode
+// only when out is a terminal.
+func shouldRender(mode string, out io.Writer) (bool, error) {
+	switch mode {
+	case renderAuto:
+		file, ok := out.(*os.File)
+		return ok && term.IsTerminal(int(file.Fd())), nil
+	case renderAlways:
+		return true, nil
+	case renderNever:
+		return false, nil
+	}
+
+	return false, fmt.Errorf("unknown render mode %q, expected %s, %s or %s", mode, renderAuto, renderAlways, renderNever)
+}
+
+// terminalWidth returns the width of the terminal out writes to, or the default width if it is not a
+// terminal.
+func terminalWidth(out io.Writer) int {
+	file, ok := out.(*os.File)
+	if !ok {
+		return defaultWidth
+	}
+
+	width, _, err := term.GetSize(int(file.Fd()))
+	if err != nil || width <= 0 {
+		return defaultWidth
+	}
+
+	return width
+}
+
+// Write receives a part of the Markdown text, the complete lines are rendered.
+func (m *markdownRenderer) Write(p []byte) (int, error) {
+	m.pending.Write(p)
+
+	for {
+		line, err := m.pending.ReadString('\n')
+		if err != nil {
+			// The line is not complete, keep it for the next write.
+			m.pending.Reset()
+			m.pending.WriteString(line)
+			return len(p), nil
+		}
+
+		m.renderLine(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// Flush renders the rest of the text, ending the open code block or table.
+func (m *markdownRenderer) Flush() {
+	if m.pending.Len() > 0 {
+		line := m.pending.String()
+		m.pending.Reset()
+		m.renderLine(line)
+	}
+
+	if m.inCode {
+		m.renderCode()
+	}
+
+	m.renderTable()
+}
+
+// renderLine renders a complete line.
+func (m *markdownRenderer) renderLine(line string) {
+	if m.inCode {
+		if strings.HasPrefix(strings.TrimSpace(line), m.fence) {
+			m.renderCode()
+			return
+		}
+
+		m.code = append(m.code, line)
+		return
+	}
+
+	if matches := fenceLine.FindStringSubmatch(line); matches != nil {
+		m.renderTable()
+		m.inCode, m.fence, m.language, m.code = true, matches[1], matches[2], nil
+		return
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(line), "|") {
+		// A table starts with a header row followed by a separator row, a row that is not followed by
+		// one is a plain line.
+		if len(m.table) == 1 && !tableSeparator.MatchString(strings.TrimSpace(line)) {
+			m.renderText(m.table[0])
+			m.table = nil
+		}
+
+		m.table = append(m.table, line)
+		return
+	}
+	m.renderTable()
+	m.renderText(line)
+}
+
+// renderText renders a line that is not part of a code block or a table.
+func (m *markdownRenderer) renderText(line string) {
+	switch {
+	case strings.TrimSpace(line) == "":
+		fmt.Fprintln(m.out)
+	case headingLine.MatchString(line):
+		matches := headingLine.FindStringSubmatch(line)
+		m.writeWrapped(styled(ansiHeading, renderInline(matches[2])), "", "")
+	case ruleLine.MatchString(line):
+		fmt.Fprintln(m.out, ansiDim+strings.Repeat("─", m.width)+ansiReset)
+	case listLine.MatchString(line):
+		matches := listLine.FindStringSubmatch(line)
+		bullet := matches[2]
+		if bullet == "-" || bullet == "*" || bullet == "+" {
+			bullet = "•"
+		}
+
+		first := matches[1] + bullet + " "
+		m.writeWrapped(renderInline(matches[3]), first, strings.Repeat(" ", visibleWidth(first)))
+	case quoteLine.MatchString(line):
+		matches := quoteLine.FindStringSubmatch(line)
+		prefix := ansiDim + "│ " + ansiReset
+		m.writeWrapped(styled(ansiItalic, renderInline(matches[1])), prefix, prefix)
+	default:
+		m.writeWrapped(renderInline(line), "", "")
+	}
+}
+
+// renderCode renders the current code block, highlighted for its language.
+func (m *markdownRenderer) renderCode() {
+	code := strings.Join(m.code, "\n") + "\n"
+	m.inCode, m.code = false, nil
+
+	if m.language != "" {
+		fmt.Fprintln(m.out, ansiDim+m.language+ansiReset)
+	}
+
+	err := quick.Highlight(m.out, code, m.language, "terminal256", codeStyle)
+	if err != nil {
+		fmt.Fprint(m.out, code)
+	}
+
+	fmt.Fprint(m.out, ansiReset)
+}
+
+// renderTable renders the current table with aligned columns, or as it is if it doesn't fit.
+func (m *markdownRenderer) renderTable() {
+	if len(m.table) == 0 {
+		return
+	}
+
+	lines := m.table
+	m.table = nil
+
+	// The row was not followed by a separator row, it is not a table.
+	if len(lines) == 1 {
+		m.renderText(lines[0])
+		return
+	}
+
+	rows := make([][]string, 0, len(lines))
+
+	for _, line := range lines {
+		if tableSeparator.MatchString(strings.TrimSpace(line)) {
+			continue
+		}
+
+		cells := splitTableRow(line)
+		for j, cell := range cells {
+			cells[j] = renderInline(strings.TrimSpace(cell))
+		}
+		rows = append(rows, cells)
+	}
+
+	widths := make([]int, 0)
+	for _, row := range rows {
+		for j, cell := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], visibleWidth(cell))
+		}
+	}
+
+	total := 0
+	for _, width := range widths {
+		total += width + 3
+	}
+
+	if total > m.width {
+		for _, line := range lines {
+			fmt.Fprintln(m.out, line)
+		}
+		return
+	}
+
+	for i, row := range rows {
+		cells := make([]string, len(widths))
+		for j := range widths {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+
+			if i == 0 {
+				cell = styled(ansiBold, cell)
+			}
+
+			cells[j] = cell + strings.Repeat(" ", widths[j]-visibleWidth(cell))
+		}
+
+		fmt.Fprintln(m.out, strings.Join(cells, ansiDim+" │ "+ansiReset))
+
+		if i == 0 {
+			separators := make([]string, len(widths))
+			for j, width := range widths {
+				separators[j] = strings.Repeat("─", width)
+			}
+			fmt.Fprintln(m.out, ansiDim+strings.Join(separators, "─┼─")+ansiReset)
+		}
+	}
+}
+
+// splitTableRow splits a row of a table in cells, on the pipes that are not escaped or in a code span.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+
+	// An unclosed backtick is not a code span.
+	unclosed := -1
+	if strings.Count(line, "`")%2 == 1 {
+		unclosed = strings.LastIndex(line, "`")
+	}
+
+	cells := make([]string, 0)
+	var cell strings.Builder
+	inCode, trailing := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		trailing = false
+
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`' && i != unclosed:
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			if i > 0 {
+				cells = append(cells, cell.String())
+			}
+			cell.Reset()
+			trailing = true
+		default:
+			cell.WriteByte(c)
+		}
+	}
+
+	// The leading and the trailing pipes are optional.
+	if !trailing {
+		cells = append(cells, cell.String())
+	}
+
+	return cells
+}
+
+// writeWrapped writes the text wrapped to the width of the terminal, the first line starts with first
+// and the next ones with rest.
+func (m *markdownRenderer) writeWrapped(text string, first string, rest string) {
+	line := first
+	length := visibleWidth(first)
+	empty := true
+
+	for _, word := range strings.Fields(text) {
+		wordLength := visibleWidth(word)
+
+		if !empty && length+1+wordLength > m.width {
+			fmt.Fprintln(m.out, line)
+			line, length, empty = rest, visibleWidth(rest), true
+		}
+
+		if !empty {
+			line += " "
+			length++
+		}
+
+		line += word
+		length += wordLength
+		empty = false
+	}
+
+	fmt.Fprintln(m.out, line)
+}
+
+// renderInline renders the inline styles: code, bold, italic, strikethrough and links.
+func renderInline(text string) string {
+	// The code spans are kept as they are, the other styles are only applied outside of them.
+	parts := strings.Split(text, "`")
+	if len(parts)%2 == 0 {
+		// An unclosed backtick is not a code span.
+		parts[len(parts)-2] += "`" + parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+
+	for i, part := range parts {
+		if i%2 == 1 {
+			parts[i] = ansiCyan + part + ansiReset
+			continue
+		}
+
+		part = linkText.ReplaceAllStringFunc(part, func(link string) string {
+			matches := linkText.FindStringSubmatch(link)
+			if matches[1] == matches[2] {
+				return ansiUnderline + matches[2] + ansiReset
+			}
+			return ansiUnderline + matches[1] + ansiReset + ansiDim + " (" + matches[2] + ")" + ansiReset
+		})
+		part = boldText.ReplaceAllString(part, ansiBold+"$1$2"+ansiReset)
+		part = italicText.ReplaceAllString(part, "$1$3"+ansiItalic+"$2$4"+ansiReset)
+		part = strikeText.ReplaceAllString(part, ansiStrike+"$1"+ansiReset)
+		parts[i] = part
+	}
+
+	return strings.Join(parts, "")
+}
+
+// styled applies the style to the whole text, including after the inline styles it contains.
+func styled(style string, text string) string {
+	return style + strings.ReplaceAll(text, ansiReset, ansiReset+style) + ansiReset
+}
+
+// visibleWidth returns the number of characters of the text displayed, without the ANSI codes.
+func visibleWidth(text string) int {
+	return utf8.RuneCountInString(ansiCode.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// render renders the Markdown and removes the ANSI codes.
+func render(markdown string) string {
+	var out bytes.Buffer
+
+	renderer := newMarkdownRenderer(&out, 80)
+	renderer.Write([]byte(markdown))
+	renderer.Flush()
+
+	return ansiCode.ReplaceAllString(out.String(), "")
+}
+
+func TestMarkdownTable(t *testing.T) {
+	got := render("| a | bb |\n|---|---|\n| ccc | d |\n")
+	want := "a   │ bb\n────┼───\nccc │ d \n"
+
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestMarkdownTableEscapedPipes(t *testing.T) {
+	got := render("| `a|b` | c\\|d |\n|---|---|\n| x | y |\n")
+	want := "a|b │ c|d\n────┼────\nx   │ y  \n"
+
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestSplitTableRow(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"| a | b |", []string{" a ", " b "}},
+		{"a | b", []string{"a ", " b"}},
+		{"| `a|b` | c |", []string{" `a|b` ", " c "}},
+		{`| a\|b | c |`, []string{" a|b ", " c "}},
+		{`| a \|`, []string{" a |"}},
+		{"| `a | b |", []string{" `a ", " b "}},
+	}
+
+	for _, test := range tests {
+		got := splitTableRow(test.line)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitTableRow(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestMarkdownPipeLinesWithoutSeparator(t *testing.T) {
+	for _, markdown := range []string{
+		"| not a table\n",
+		"| first\n| second\n",
+		"| shell | pipe\ntext after\n",
+	} {
+		got := render(markdown)
+
+		if strings.Contains(got, "│") {
+			t.Errorf("%q was rendered as a table:\n%s", markdown, got)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(markdown), "\n") {
+			if !strings.Contains(got, line) {
+				t.Errorf("%q is missing from the output of %q:\n%s", line, markdown, got)
+			}
+		}
+	}
+}
+
+func TestShouldRenderAutoWithoutTerminal(t *testing.T) {
+	render, err := shouldRender(renderAuto, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if render {
+		t.Error("the Markdown must not be rendered to a writer that is not a terminal")
+	}
+}
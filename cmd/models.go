@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+	"github.com/spf13/cobra"
+)
+
+// modelsCmd groups the commands to manage the models.
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Manage the models",
+	Long: `Manage the models of the Ollama configured in assistants.ollama.endpoint. When the active plugin is
+openai, list prints the models of the OpenAI API instead.`,
+}
+
+// modelsListCmd lists the models.
+var modelsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the models of the active plugin",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(false)
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+
+		if cfg.Assistants.Plugin == "openai" {
+			err = config.ValidateOpenAI(cfg)
+			if err != nil {
+				return withExitCode(exitConfig, "%w", err)
+			}
+
+			client, err := assistants.NewOpenAIClient(cfg)
+			if err != nil {
+				return withExitCode(exitConfig, "%w", err)
+			}
+
+			models, err := client.ListModels(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, "ID\tOWNED BY\tCREATED")
+			for _, model := range models.Models {
+				fmt.Fprintf(out, "%s\t%s\t%s\n", model.ID, model.OwnedBy, time.Unix(model.CreatedAt, 0).Format(time.DateOnly))
+			}
+
+			return out.Flush()
+		}
+
+		err = config.ValidateOllama(cfg)
+		if err != nil {
+			return withExitCode(exitConfig, "%w", err)
+		}
+
+		models, err := ollamaClient(cfg).ListModels(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, "NAME\tSIZE\tPARAMETERS\tQUANTIZATION\tMODIFIED")
+		for _, model := range models.Models {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n",
+				model.Name,
+				formatBytes(model.Size),
+				model.Details.ParameterSize,
+				model.Details.QuantizationLevel,
+				model.ModifiedAt.Local().Format(time.DateTime),
+			)
+		}
+
+		return out.Flush()
+	},
+}
+
+// modelsShowCmd prints the details of a model.
+var modelsShowCmd = &cobra.Command{
+	Use:          "show <name>",
+	Short:        "Print the details of a model",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(true)
+		if err != nil {
+			return err
+		}
+
+		model, err := ollamaClient(cfg).ShowModel(cmd.Context(), &ollama.ShowModelRequest{Name: args[0]})
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+
+		modelfile, _ := cmd.Flags().GetBool("modelfile")
+		if modelfile {
+			fmt.Fprint(out, model.ModelFile)
+			return nil
+		}
+
+		details := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(details, "Family\t%s\n", model.Details.Family)
+		fmt.Fprintf(details, "Parameters\t%s\n", model.Details.ParameterSize)
+		fmt.Fprintf(details, "Quantization\t%s\n", model.Details.QuantizationLevel)
+		fmt.Fprintf(details, "Format\t%s\n", model.Details.Format)
+		details.Flush()
+
+		if model.Parameters != "" {
+			fmt.Fprintf(out, "\nParameters:\n%s\n", model.Parameters)
+		}
+
+		if model.Template != "" {
+			fmt.Fprintf(out, "\nTemplate:\n%s\n", model.Template)
+		}
+
+		return nil
+	},
+}
+
+// pullAttempts is the number of times a pull is attempted when the download is interrupted.
+const pullAttempts = 3
+
+// modelsPullCmd downloads a model.
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull <name>",
+	Short: "Download a model, showing its progress",
+	Long: `Download a model, showing the progress of each layer with its speed and remaining time. An
+interrupted download is attempted again, the Ollama resumes it where it stopped. After Ctrl-C, run the
+pull again to resume it.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(true)
+		if err != nil {
+			return err
+		}
+
+		progress := &pullProgress{out: cmd.ErrOrStderr()}
+		defer progress.Done()
+
+		err = ollamaClient(cfg).PullModelWithRetry(cmd.Context(), &ollama.PullModelRequest{Name: args[0]}, pullAttempts, progress.Update)
+		if errors.Is(err, ollama.ErrPullInterrupted) {
+			return fmt.Errorf("%w, run the pull again to resume it", err)
+		}
+
+		return err
+	},
+}
+
+// modelsRmCmd deletes models.
+var modelsRmCmd = &cobra.Command{
+	Use:          "rm <name>...",
+	Short:        "Delete models",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(true)
+		if err != nil {
+			return err
+		}
+
+		client := ollamaClient(cfg)
+
+		for _, name := range args {
+			err := client.DeleteModel(cmd.Context(), &ollama.DeleteModelRequest{Name: name})
+			if err != nil {
+				return fmt.Errorf("deleting %s: %w", name, err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %s\n", name)
+		}
+
+		return nil
+	},
+}
+
+// modelsCpCmd copies a model.
+var modelsCpCmd = &cobra.Command{
+	Use:          "cp <source> <destination>",
+	Short:        "Copy a model",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(true)
+		if err != nil {
+			return err
+		}
+
+		err = ollamaClient(cfg).CopyModel(cmd.Context(), &ollama.CopyModelRequest{Source: args[0], Destination: args[1]})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Copied %s to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+// modelsPsCmd lists the models loaded in memory.
+var modelsPsCmd = &cobra.Command{
+	Use:          "ps",
+	Short:        "List the models loaded in memory",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := modelsConfig(true)
+		if err != nil {
+			return err
+		}
+
+		models, err := ollamaClient(cfg).ListRunningModels(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "NAME\tSIZE\tPROCESSOR\tUNTIL")
+
+		for _, model := range models.Models {
+			processor := "100% CPU"
+			if model.Size > 0 && model.SizeVRAM > 0 {
+				gpu := model.SizeVRAM * 100 / model.Size
+				processor = fmt.Sprintf("%d%% GPU", gpu)
+				if gpu < 100 {
+					processor = fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+				}
+			}
+
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", model.Name, formatBytes(model.Size), processor, model.ExpiresAt.Local().Format(time.DateTime))
+		}
+
+		return out.Flush()
+	},
+}
+
+// modelsConfig loads the configuration of the models commands. They don't need the core, and only
+// the Ollama configuration is validated unless the models of the OpenAI are listed: a problem elsewhere
+// doesn't prevent managing the models.
+func modelsConfig(validateOllama bool) (*config.Base, error) {
+	cfg, err := config.GetCurrentConfigurations()
+	if err != nil {
+		return nil, withExitCode(exitConfig, "loading the configuration: %w", err)
+	}
+
+	if validateOllama {
+		err = config.ValidateOllama(cfg)
+		if err != nil {
+			return nil, withExitCode(exitConfig, "%w", err)
+		}
+	}
+
+	err = core.ConfigureLogging(cfg.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ollamaClient creates a client of the configured Ollama.
+func ollamaClient(cfg *config.Base) *ollama.OllamaClient {
+	return assistants.NewOllamaClient(cfg)
+}
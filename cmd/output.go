@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// outputText prints the answers as they are streamed.
+	outputText = "text"
+	// outputJSON prints a JSON object per answer, when it is done.
+	outputJSON = "json"
+	// outputNDJSON prints a JSON object per event, one per line, as they happen.
+	outputNDJSON = "ndjson"
+)
+
+// turnResult is the JSON object printed for an answer with --output json.
+type turnResult struct {
+	// Content is the answer.
+	Content string `json:"content"`
+	// ToolCalls are the tools called to answer.
+	ToolCalls []assistants.Event `json:"tool_calls"`
+	// Usage is the number of tokens used, when the backend reports it.
+	Usage *assistants.Usage `json:"usage,omitempty"`
+	// Error is the error of the request, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// turnOutput prints the answers of the assistant in an output format. The answers and the events are
+// printed to out, the errors of the text format to errOut unless it is nil.
+type turnOutput struct {
+	// format is the output format, one of the output constants.
+	format string
+	// out is where the answers and the events are printed.
+	out io.Writer
+	// errOut is where the errors of the text format are printed.
+	errOut io.Writer
+	// encoder encodes the events and the results.
+	encoder *json.Encoder
+	// result is the result of the current answer.
+	result turnResult
+	// content is the answer being streamed.
+	content strings.Builder
+	// err is the error of the current answer.
+	err error
+	// renderer renders the Markdown of the text format, nil to print it as it is.
+	renderer *markdownRenderer
+	// progress prints the progress of the model pulls of the text format, nil to hide it.
+	progress *pullProgress
+}
+
+// newTurnOutput creates a turnOutput, checking the format.
+func newTurnOutput(format string, out io.Writer, errOut io.Writer) (*turnOutput, error) {
+	switch format {
+	case outputText, outputJSON, outputNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected %s, %s or %s", format, outputText, outputJSON, outputNDJSON)
+	}
+
+	return &turnOutput{
+		format:  format,
+		out:     out,
+		errOut:  errOut,
+		encoder: json.NewEncoder(out),
+	}, nil
+}
+
+// RenderMarkdown renders the Markdown of the answers of the text format for a terminal of the width.
+func (t *turnOutput) RenderMarkdown(width int) {
+	if t.format == outputText {
+		t.renderer = newMarkdownRenderer(t.out, width)
+	}
+}
+
+// ShowPullProgress prints the progress of the model pulls of the text format to out.
+func (t *turnOutput) ShowPullProgress(out io.Writer) {
+	if t.format == outputText {
+		t.progress = &pullProgress{out: out}
+	}
+}
+
+// Structured checks if the output is made of JSON objects.
+func (t *turnOutput) Structured() bool {
+	return t.format != outputText
+}
+
+// Start starts a new answer.
+func (t *turnOutput) Start() {
+	t.result = turnResult{ToolCalls: make([]assistants.Event, 0)}
+	t.content.Reset()
+	t.err = nil
+}
+
+// Callback receives the output of the assistant, it is given to SendRequest.
+func (t *turnOutput) Callback(output string, err error) {
+	if err != nil {
+		t.Fail(err)
+		return
+	}
+
+	t.content.WriteString(output)
+
+	switch t.format {
+	case outputText:
+		if t.renderer != nil {
+			t.renderer.Write([]byte(output))
+		} else {
+			fmt.Fprint(t.out, output)
+		}
+	case outputNDJSON:
+		t.encoder.Encode(assistants.Event{Type: assistants.EventToken, Content: output})
+	}
+}
+
+// Handle receives the events of the assistant, it is given to SetEventHandler.
+func (t *turnOutput) Handle(event assistants.Event) {
+	switch event.Type {
+	case assistants.EventToolCallFinished:
+		t.result.ToolCalls = append(t.result.ToolCalls, event)
+	case assistants.EventUsage:
+		t.result.Usage = event.Usage
+	case assistants.EventPullProgress:
+		if t.progress != nil {
+			t.showPullProgress(event.Pull)
+		}
+	}
+
+	if t.format == outputNDJSON {
+		t.encoder.Encode(event)
+	}
+}
+
+// showPullProgress prints the progress of a model pull, the line ends when the model is ready.
+func (t *turnOutput) showPullProgress(pull *assistants.PullProgress) {
+	t.progress.Update(&ollama.PullModelResponse{
+		Status:    pull.Status,
+		Digest:    pull.Digest,
+		Total:     pull.Total,
+		Completed: pull.Completed,
+	})
+
+	if pull.Status == ollama.PullStatusSuccess {
+		t.progress.Done()
+	}
+}
+
+// Fail reports the error of the answer.
+func (t *turnOutput) Fail(err error) {
+	t.err = errors.Join(t.err, err)
+
+	switch t.format {
+	case outputText:
+		if t.errOut != nil {
+			fmt.Fprintln(t.errOut, "Error sending request:", err)
+		}
+	case outputNDJSON:
+		t.encoder.Encode(assistants.Event{Type: assistants.EventError, Error: err.Error()})
+	}
+}
+
+// Finish ends the answer, returning its error.
+func (t *turnOutput) Finish() error {
+	content := strings.TrimSpace(t.content.String())
+
+	switch t.format {
+	case outputText:
+		if t.renderer != nil {
+			t.renderer.Flush()
+		} else if t.content.Len() > 0 && !strings.HasSuffix(t.content.String(), "\n") {
+			fmt.Fprintln(t.out)
+		}
+	case outputJSON:
+		t.result.Content = content
+		if t.err != nil {
+			t.result.Error = t.err.Error()
+		}
+		t.encoder.Encode(t.result)
+	case outputNDJSON:
+		if t.err == nil {
+			t.encoder.Encode(assistants.Event{Type: assistants.EventMessage, Content: content})
+		}
+	}
+
+	return t.err
+}
+
+// Error reports an error outside of an answer, like the error of a command.
+func (t *turnOutput) Error(err error) {
+	if t.format == outputText {
+		fmt.Fprintln(t.errOut, "Error:", err)
+		return
+	}
+
+	t.encoder.Encode(assistants.Event{Type: assistants.EventError, Error: err.Error()})
+}
+
+// newCommandOutput creates the turnOutput of the --output and --render flags of the command. The errors
+// of the text format are printed to errOut unless it is nil.
+func newCommandOutput(cmd *cobra.Command, errOut io.Writer) (*turnOutput, error) {
+	format, _ := cmd.Flags().GetString("output")
+
+	output, err := newTurnOutput(format, cmd.OutOrStdout(), errOut)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, _ := cmd.Flags().GetString("render")
+
+	render, err := shouldRender(mode, cmd.OutOrStdout())
+	if err != nil {
+		return nil, err
+	}
+
+	if render {
+		output.RenderMarkdown(terminalWidth(cmd.OutOrStdout()))
+	}
+
+	output.ShowPullProgress(cmd.ErrOrStderr())
+
+	return output, nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+)
+
+// progressBarWidth is the number of characters of the progress bars.
+const progressBarWidth = 30
+
+// pullProgress prints the progress of a model pull: a line per status, with a progress bar, the speed
+// and the remaining time for the layers being downloaded.
+type pullProgress struct {
+	// out is where the progress is printed.
+	out io.Writer
+	// line is the status or layer of the current line, a new line is started when it changes.
+	line string
+	// length is the length of the current line, to clear it when it is printed again shorter.
+	length int
+	// started is when the current layer started downloading.
+	started time.Time
+	// startCompleted is the number of bytes of the current layer already downloaded when it started,
+	// when a pull is resumed.
+	startCompleted int64
+}
+
+// Update prints a status of the pull.
+func (p *pullProgress) Update(status *ollama.PullModelResponse) error {
+	line := status.Status
+	if status.Digest != "" {
+		line = status.Digest
+	}
+
+	if p.line != line {
+		if p.line != "" {
+			fmt.Fprintln(p.out)
+		}
+
+		p.line, p.length = line, 0
+		p.started, p.startCompleted = time.Now(), status.Completed
+	}
+
+	if status.Total <= 0 {
+		p.print(status.Status)
+		return nil
+	}
+
+	completed := min(status.Completed, status.Total)
+	filled := int(completed * progressBarWidth / status.Total)
+
+	text := fmt.Sprintf("%s %3d%% [%s%s] %s/%s",
+		status.Status,
+		completed*100/status.Total,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		formatBytes(completed),
+		formatBytes(status.Total),
+	)
+
+	if rate, eta, ok := p.estimate(completed, status.Total); ok {
+		text += fmt.Sprintf(" %s/s ETA %s", formatBytes(int64(rate)), eta)
+	}
+
+	p.print(text)
+	return nil
+}
+
+// estimate returns the download speed in bytes per second and the remaining time of the current layer,
+// once it has been downloading long enough to measure them.
+func (p *pullProgress) estimate(completed int64, total int64) (float64, time.Duration, bool) {
+	elapsed := time.Since(p.started)
+	downloaded := completed - p.startCompleted
+
+	if elapsed < time.Second || downloaded <= 0 || completed >= total {
+		return 0, 0, false
+	}
+
+	rate := float64(downloaded) / elapsed.Seconds()
+	eta := time.Duration(float64(total-completed) / rate * float64(time.Second))
+
+	return rate, eta.Round(time.Second), true
+}
+
+// print prints the current line again.
+func (p *pullProgress) print(text string) {
+	padding := ""
+	if length := len(text); length < p.length {
+		padding = strings.Repeat(" ", p.length-length)
+	}
+
+	fmt.Fprintf(p.out, "\r%s%s", text, padding)
+	p.length = len(text)
+}
+
+// Done ends the current line.
+func (p *pullProgress) Done() {
+	if p.line != "" {
+		fmt.Fprintln(p.out)
+		p.line, p.length = "", 0
+	}
+}
+
+// formatBytes formats a size in bytes, like 4.1 GB.
+func formatBytes(size int64) string {
+	const unit = 1000
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// prompt is the prompt of the first line of an input.
+	prompt = "You: "
+	// continuationPrompt is the prompt of the next lines of a multi-line input.
+	continuationPrompt = "...  "
+	// continuationSuffix continues the input on the next line when it ends a line.
+	continuationSuffix = `\`
+	// blockDelimiter starts and ends a block of lines, to paste multi-line text.
+	blockDelimiter = `"""`
+)
+
+// repl reads the inputs of the interactive CLI, with line editing and a persistent history.
+type repl struct {
+	// line is the line editor.
+	line *liner.State
+	// historyPath is the file the history is loaded from and saved to.
+	historyPath string
+	// prompt is the prompt of the first line of an input.
+	prompt string
+	// continuationPrompt is the prompt of the next lines of a multi-line input.
+	continuationPrompt string
+}
+
+// newREPL creates a repl, loading the history from the file if it exists. Without showPrompts, the
+// lines are read without any prompt.
+func newREPL(historyPath string, showPrompts bool) *repl {
+	line := liner.NewLiner()
+	line.SetCtrlCAborts(true)
+	line.SetMultiLineMode(true)
+
+	if file, err := os.Open(historyPath); err == nil {
+		_, err := line.ReadHistory(file)
+		if err != nil {
+			log.Warnf("Reading the history: %v", err)
+		}
+		file.Close()
+	}
+
+	r := &repl{
+		line:        line,
+		historyPath: historyPath,
+	}
+
+	if showPrompts {
+		r.prompt, r.continuationPrompt = prompt, continuationPrompt
+	}
+
+	return r
+}
+
+// ReadInput reads an input. A line ending with \ continues on the next line, and the lines between two
+// """ lines are read as a block. Ctrl-C discards the current input, io.EOF is returned on Ctrl-D.
+func (r *repl) ReadInput() (string, error) {
+	lines := make([]string, 0)
+	current := r.prompt
+	block := false
+
+	for {
+		line, err := r.line.Prompt(current)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			lines, current, block = lines[:0], r.prompt, false
+			continue
+		}
+
+		if err != nil {
+			// An unfinished input is still sent when the input ends.
+			if errors.Is(err, io.EOF) && len(lines) > 0 {
+				return r.finish(lines), nil
+			}
+
+			return "", err
+		}
+
+		switch {
+		case strings.TrimSpace(line) == blockDelimiter:
+			if block {
+				return r.finish(lines), nil
+			}
+			block = true
+		case block:
+			lines = append(lines, line)
+		case strings.HasSuffix(line, continuationSuffix):
+			lines = append(lines, strings.TrimSuffix(line, continuationSuffix))
+		default:
+			return r.finish(append(lines, line)), nil
+		}
+
+		current = r.continuationPrompt
+	}
+}
+
+// finish joins the lines of an input and adds it to the history.
+func (r *repl) finish(lines []string) string {
+	input := strings.Join(lines, "\n")
+
+	// The history is saved one entry per line.
+	if entry := strings.Join(strings.Fields(input), " "); entry != "" {
+		r.line.AppendHistory(entry)
+	}
+
+	return input
+}
+
+// Close saves the history and restores the terminal.
+func (r *repl) Close() error {
+	defer r.line.Close()
+
+	err := os.MkdirAll(filepath.Dir(r.historyPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(r.historyPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = r.line.WriteHistory(file)
+	return err
+}
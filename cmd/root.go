@@ -4,9 +4,18 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Pishia-IA/core/config"
 	"github.com/Pishia-IA/core/core"
 	"github.com/Pishia-IA/core/plugins/assistants"
 	"github.com/spf13/cobra"
@@ -14,24 +23,6 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-type newline struct{ tok string }
-
-func (n *newline) Scan(state fmt.ScanState, verb rune) error {
-	tok, err := state.Token(false, func(r rune) bool {
-		return r != '\n'
-	})
-	if err != nil {
-		return err
-	}
-	if _, _, err := state.ReadRune(); err != nil {
-		if len(tok) == 0 {
-			panic(err)
-		}
-	}
-	n.tok = string(tok)
-	return nil
-}
-
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "pishia",
@@ -39,71 +30,220 @@ var rootCmd = &cobra.Command{
 	Long: `Pishia is an open source alternative to Google Assistant, Amazon Alexa, and Apple Siri.
 It is a CLI tool that allows you to create your own personal assistant with custom commands and responses.
 You can use it to automate tasks, get information, and more.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		config.SetProfile(profile)
+		config.SetConfigFile(flagConfigPath(cmd))
+
+		level, _ := cmd.Flags().GetString("log-level")
+		format, _ := cmd.Flags().GetString("log-format")
+		file, _ := cmd.Flags().GetString("log-file")
+		core.SetLogFlags(config.Log{Level: level, Format: format, File: file})
+
+		// The configuration is not loaded yet, the flags apply until it is.
+		return core.ConfigureLogging(config.Log{})
+	},
 }
 
 // cli is an action that you can use to run the CLI.
 var cliCmd = &cobra.Command{
 	Use:   "cli",
 	Short: "Run the Pishia CLI",
-	Long:  `Run the Pishia CLI to create your own personal assistant with custom commands and responses.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		log.SetLevel(log.DebugLevel)
-		err := core.Boot()
+	Long: `Run the Pishia CLI to create your own personal assistant with custom commands and responses.
+
+End a line with \ to continue the input on the next line, or paste multi-line text between two """
+lines. The history is kept in the data directory. With --output json or ndjson, the answers are printed
+as JSON objects like with the ask command, and the prompts are left out. Exit with Ctrl-D or /exit, type /help to list
+the other commands.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		core.SetSessionID(core.NewSessionID())
+
+		output, err := newCommandOutput(cmd, cmd.ErrOrStderr())
+		if err != nil {
+			return withExitCode(exitUsage, "%w", err)
+		}
+
+		err = core.Boot()
 		if err != nil {
-			cmd.Println("Error booting the core:", err)
-			return
+			return withExitCode(exitConfig, "booting the core: %w", err)
 		}
+
 		assistant := assistants.GetDefaultAssistant()
-		err = assistant.Setup()
+		if assistant == nil {
+			return withExitCode(exitConfig, "booting the core: no assistant available")
+		}
 
+		// The handler is set first to show the progress of the model pull, if any.
+		assistant.SetEventHandler(output.Handle)
+
+		err = assistant.Setup(cmd.Context())
 		if err != nil {
-			cmd.Println("Error setting up the Ollama:", err)
-			return
+			return withExitCode(exitUnavailable, "setting up the assistant: %w", err)
 		}
 
-		for {
-			cmd.Print("You: ")
-			var n newline
-			fmt.Scan(&n)
+		// turn is held while the assistant answers, so the configuration is never reloaded mid-answer.
+		var turn sync.Mutex
 
-			cmd.Print("Pishia: ")
+		watch, _ := cmd.Flags().GetBool("watch")
+		if watch {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
 
-			if err != nil {
-				cmd.Println("Error reading input:", err)
-				return
+			go config.Watch(ctx, time.Second, core.Names(), func(cfg *config.Base) error {
+				turn.Lock()
+				defer turn.Unlock()
+				return core.Reload(ctx, cfg)
+			})
+		}
+
+		// The prompts are left out of the structured outputs, only the events are printed to stdout.
+		repl := newREPL(filepath.Join(config.DataDir(), "history"), !output.Structured())
+		defer func() {
+			if err := repl.Close(); err != nil {
+				log.Warnf("Saving the history: %v", err)
 			}
+		}()
+
+		s := &session{
+			out: cmd.OutOrStdout(),
+			send: func(input string) error {
+				if !output.Structured() {
+					fmt.Fprint(cmd.OutOrStdout(), "Pishia: ")
+				}
+
+				assistant := assistants.GetDefaultAssistant()
+				assistant.SetEventHandler(output.Handle)
+
+				// Ctrl-C stops the answer instead of exiting.
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+				defer stop()
 
-			printResponse := func(output string, err error) {
+				output.Start()
+				err := assistant.SendRequest(ctx, input, output.Callback)
 				if err != nil {
-					cmd.Println("Error sending request:", err)
-					return
+					output.Fail(err)
 				}
 
-				cmd.Print(output)
-			}
+				// The error has already been reported.
+				output.Finish()
+
+				if !output.Structured() {
+					fmt.Fprintln(cmd.OutOrStdout())
+				}
+				return nil
+			},
+		}
+
+		if output.Structured() {
+			s.out = cmd.ErrOrStderr()
+		}
 
-			err := assistant.SendRequest(n.tok, printResponse)
+		for {
+			input, err := repl.ReadInput()
+			if errors.Is(err, io.EOF) {
+				cmd.Println()
+				return nil
+			}
 
 			if err != nil {
-				cmd.Println("Error sending request:", err)
-				return
+				return withExitCode(exitFailure, "reading the input: %w", err)
 			}
 
-			cmd.Println()
-		}
+			input = strings.TrimSpace(input)
+			if input == "" {
+				continue
+			}
+
+			turn.Lock()
+			if isSlashCommand(input) {
+				err = runSlashCommand(s, input)
+			} else {
+				err = s.send(input)
+			}
+			turn.Unlock()
 
+			if errors.Is(err, errExit) {
+				return nil
+			}
+
+			if err != nil {
+				output.Error(err)
+			}
+		}
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.PersistentFlags().String("config", "", "Configuration file applied on top of the others, and changed by the config commands")
+	rootCmd.PersistentFlags().String("log-level", "", "Minimum level of the logs: trace, debug, info, warn or error, overrides log.level")
+	rootCmd.PersistentFlags().String("log-format", "", "Format of the logs: text or json, overrides log.format")
+	rootCmd.PersistentFlags().String("log-file", "", "File the logs are written to, relative to the state directory, overrides log.file")
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use, overrides PISHIA_PROFILE")
+
 	// Add the CLI command.
+	cliCmd.Flags().Bool("watch", true, "Reload the configuration when the file changes")
+	cliCmd.Flags().StringP("output", "o", outputText, "Output format: text, json or ndjson")
+	cliCmd.Flags().String("render", renderAuto, "Render the Markdown of the answers: auto (when stdout is a terminal), always or never")
 	rootCmd.AddCommand(cliCmd)
 
+	// Add the ask command.
+	askCmd.Flags().String("model", "", "Model to use instead of the configured one")
+	askCmd.Flags().String("plugin", "", "Assistant plugin to use instead of the configured one")
+	askCmd.Flags().Bool("no-tools", false, "Answer without calling any tool")
+	askCmd.Flags().String("system", "", "System prompt to use instead of the configured one")
+	askCmd.Flags().StringP("output", "o", outputText, "Output format: text, json or ndjson")
+	askCmd.Flags().String("render", renderAuto, "Render the Markdown of the answers: auto (when stdout is a terminal), always or never")
+	askCmd.Flags().Float64("temperature", 0, "Randomness of the answer from 0 to 2, overrides assistants.generation.temperature")
+	askCmd.Flags().Float64("top-p", 0, "Cumulative probability of the tokens kept from 0 to 1, overrides assistants.generation.top_p")
+	askCmd.Flags().Int("max-tokens", 0, "Maximum number of tokens of the answer, overrides assistants.generation.max_tokens")
+	askCmd.Flags().Int("num-ctx", 0, "Size of the context window in tokens, overrides assistants.generation.num_ctx")
+	askCmd.Flags().Int("seed", 0, "Seed making the answer reproducible, overrides assistants.generation.seed")
+	askCmd.Flags().StringArray("stop", nil, "Sequence ending the answer, can be repeated, overrides assistants.generation.stop")
+	askCmd.Flags().Float64("repeat-penalty", 0, "Penalty of the repeated tokens, overrides assistants.generation.repeat_penalty")
+	askCmd.Flags().String("format", "", "Format of the answer: text or json, overrides assistants.generation.format")
+	rootCmd.AddCommand(askCmd)
+
+	// Add the tools commands.
+	toolsRunCmd.Flags().StringArray("arg", nil, "Argument of the tool as key=value, can be repeated")
+	toolsRunCmd.Flags().String("json", "", "Arguments of the tool as a JSON object")
+	toolsRunCmd.Flags().String("query", "", "User query given to the tool, the search argument by default")
+	toolsRunCmd.Flags().Bool("summarize", false, "Summarize the prompts of the response with the default assistant")
+	toolsCmd.AddCommand(toolsListCmd, toolsSchemaCmd, toolsRunCmd)
+	rootCmd.AddCommand(toolsCmd)
+
+	// Add the models commands.
+	modelsShowCmd.Flags().Bool("modelfile", false, "Print the Modelfile of the model")
+	modelsCmd.AddCommand(modelsListCmd, modelsShowCmd, modelsPullCmd, modelsRmCmd, modelsCpCmd, modelsPsCmd)
+	rootCmd.AddCommand(modelsCmd)
+
+	// Add the serve command.
+	serveCmd.Flags().String("address", "", "Address to listen on, overrides server.address")
+	serveCmd.Flags().StringSlice("allowed-origin", nil, "Origin allowed to call the API from a browser, can be repeated, overrides server.allowed_origins")
+	rootCmd.AddCommand(serveCmd)
+
+	// Add the config commands.
+	configPathCmd.Flags().Bool("all", false, "Print every configuration file and the data, cache and state directories")
+	configGetCmd.Flags().Bool("reveal", false, "Print the secrets instead of masking them")
+	configInitCmd.Flags().Bool("force", false, "Overwrite the configuration file without asking")
+	configMigrateCmd.Flags().Bool("dry-run", false, "Print the migrated configuration without writing it")
+	configCmd.AddCommand(configPathCmd, configShowCmd, configGetCmd, configSetCmd, configInitCmd, configValidateCmd, configEditCmd, configProfilesCmd, configMigrateCmd)
+	rootCmd.AddCommand(configCmd)
+
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
+
+		if errors.Is(err, config.ErrConfigNotFound) {
+			os.Exit(exitConfig)
+		}
+
+		os.Exit(exitFailure)
 	}
 
 }
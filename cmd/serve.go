@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/server"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd serves the HTTP API.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the HTTP API, to use Pishia from a web or mobile front end",
+	Long: `Serve the HTTP API. Each session has its own assistant, created from the configuration when the
+session is created:
+
+  GET    /sessions                 list the sessions
+  POST   /sessions                 create a session, {"plugin", "model", "system", "options"} are optional
+  GET    /sessions/{id}            get a session with its messages
+  DELETE /sessions/{id}            delete a session
+  POST   /sessions/{id}/messages   send {"content"} and stream the answer as Server-Sent Events
+  GET    /tools                    list the tools the assistants can call
+  GET    /health                   check the server is up, without authentication
+
+The options of a session override assistants.generation, like {"temperature": 0.2, "seed": 42}. The
+answers are streamed with the same events as ask --output ndjson: token, tool_call_started,
+tool_call_finished, usage, then message or error.
+
+The models of the sessions must be installed, they are not pulled. At most server.max_sessions (100)
+sessions are open, and a session without messages for server.session_idle_timeout (1h) is deleted.
+
+The server is also compatible with the OpenAI API, so the OpenAI clients can use Pishia with its tools
+by pointing their base URL to http://<address>/v1:
+
+  POST   /v1/chat/completions      answer a conversation, streamed when "stream" is true
+  GET    /v1/models                list the models of the configured plugin
+
+The temperature, top_p, max_tokens, seed, stop and response_format of the completions override
+assistants.generation.
+
+The requests must send server.token (or PISHIA_SERVER_TOKEN) as a bearer token. When it is not set, a
+random token is generated and printed at every start. The bodies must be sent as application/json, and
+the host of the requests must be the address of the server, localhost or one of server.allowed_hosts.
+The browsers can call the API from server.allowed_origins. On SIGINT or SIGTERM, the server waits for
+the running answers to end, up to server.shutdown_timeout.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := core.BootWith(func(cfg *config.Base) {
+			if address, _ := cmd.Flags().GetString("address"); address != "" {
+				cfg.Server.Address = address
+			}
+
+			if origins, _ := cmd.Flags().GetStringSlice("allowed-origin"); len(origins) > 0 {
+				cfg.Server.AllowedOrigins = origins
+			}
+		})
+		if err != nil {
+			return withExitCode(exitConfig, "booting the core: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return server.NewServer(core.Config().Server).Run(ctx)
+	},
+}
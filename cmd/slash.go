@@ -0,0 +1,476 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/plugins/tools"
+)
+
+var (
+	// slashCommands are the built-in commands of the interactive CLI, indexed by name.
+	slashCommands = make(map[string]slashCommand)
+	// errExit is returned by /exit to end the session.
+	errExit = errors.New("exit")
+)
+
+// slashCommand is a command of the interactive CLI, run with /name.
+type slashCommand struct {
+	// name is the name of the command, without the slash.
+	name string
+	// usage describes the arguments of the command.
+	usage string
+	// description describes what the command does.
+	description string
+	// run runs the command with the rest of the input line.
+	run func(s *session, args string) error
+}
+
+// session is the state of the interactive CLI, shared by the commands.
+type session struct {
+	// out is where the answers and the output of the commands are written.
+	out io.Writer
+	// send sends an input to the default assistant and prints the answer.
+	send func(input string) error
+	// system is the system prompt set with /system, it is kept when the assistant is switched.
+	system string
+}
+
+// registerSlashCommand registers a built-in command, there can only be one command with each name.
+func registerSlashCommand(command slashCommand) {
+	if _, ok := slashCommands[command.name]; ok {
+		panic(fmt.Sprintf("slash command /%s already registered", command.name))
+	}
+
+	slashCommands[command.name] = command
+}
+
+// availableSlashCommands returns the built-in commands and the commands of the registered tools, sorted
+// by name. The built-in commands win over the tool commands with the same name.
+func availableSlashCommands() []slashCommand {
+	commands := make(map[string]slashCommand, len(slashCommands))
+
+	if repository := tools.GetRepository(); repository != nil {
+		for _, command := range repository.Commands() {
+			run := command.Run
+			commands[command.Name] = slashCommand{
+				name:        command.Name,
+				usage:       command.Usage,
+				description: command.Description,
+				run: func(s *session, args string) error {
+					return run(args, s.out)
+				},
+			}
+		}
+	}
+
+	for name, command := range slashCommands {
+		commands[name] = command
+	}
+
+	sorted := make([]slashCommand, 0, len(commands))
+	for _, command := range commands {
+		sorted = append(sorted, command)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+
+	return sorted
+}
+
+// isSlashCommand checks if the input is a command instead of a message.
+func isSlashCommand(input string) bool {
+	return strings.HasPrefix(input, "/") && !strings.HasPrefix(input, "//")
+}
+
+// runSlashCommand runs the command of the input line.
+func runSlashCommand(s *session, input string) error {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, "/"), " ")
+	args = strings.TrimSpace(args)
+
+	for _, command := range availableSlashCommands() {
+		if command.name == name {
+			return command.run(s, args)
+		}
+	}
+
+	return fmt.Errorf("unknown command /%s, type /help to list the commands", name)
+}
+
+// switchAssistant reloads the core with a change of the current configuration, the conversation and
+// the system prompt set with /system are kept.
+func switchAssistant(s *session, change func(cfg *config.Base)) error {
+	current := core.Config()
+	if current == nil {
+		return fmt.Errorf("no configuration loaded")
+	}
+
+	cfg := *current
+	change(&cfg)
+
+	err := config.Validate(&cfg, core.Names())
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C stops the pull of a missing model instead of exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = core.Reload(ctx, &cfg)
+	if err != nil {
+		return err
+	}
+
+	// The new assistant starts with the system prompt of the configuration.
+	if s.system != "" {
+		assistant, err := defaultAssistant()
+		if err != nil {
+			return err
+		}
+
+		replaceSystemPrompt(assistant, s.system)
+	}
+
+	return nil
+}
+
+// replaceSystemPrompt replaces the system prompt of the assistant, the rest of the conversation is kept.
+func replaceSystemPrompt(assistant assistants.Assistant, prompt string) {
+	messages := []assistants.Message{{Role: "system", Content: prompt}}
+	for _, message := range assistant.History() {
+		if message.Role != "system" {
+			messages = append(messages, message)
+		}
+	}
+
+	assistant.SetHistory(messages)
+}
+
+// defaultAssistant returns the default assistant, or an error if there is none.
+func defaultAssistant() (assistants.Assistant, error) {
+	assistant := assistants.GetDefaultAssistant()
+	if assistant == nil {
+		return nil, fmt.Errorf("no assistant available")
+	}
+
+	return assistant, nil
+}
+
+// sessionPath returns the path of a saved session, a bare name is kept in the sessions directory.
+func sessionPath(name string) string {
+	if name == "" {
+		name = time.Now().Format("20060102-150405")
+	}
+
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return name
+	}
+
+	if filepath.Ext(name) == "" {
+		name += ".json"
+	}
+
+	return filepath.Join(config.DataDir(), "sessions", name)
+}
+
+func init() {
+	registerSlashCommand(slashCommand{
+		name:        "help",
+		description: "List the commands",
+		run: func(s *session, args string) error {
+			for _, command := range availableSlashCommands() {
+				usage := "/" + command.name
+				if command.usage != "" {
+					usage += " " + command.usage
+				}
+
+				fmt.Fprintf(s.out, "  %-24s %s\n", usage, command.description)
+			}
+
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "exit",
+		description: "End the session",
+		run: func(s *session, args string) error {
+			return errExit
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "reset",
+		description: "Clear the conversation, keeping the system prompt",
+		run: func(s *session, args string) error {
+			assistant, err := defaultAssistant()
+			if err != nil {
+				return err
+			}
+
+			messages := make([]assistants.Message, 0)
+			for _, message := range assistant.History() {
+				if message.Role == "system" {
+					messages = append(messages, message)
+				}
+			}
+
+			assistant.SetHistory(messages)
+			fmt.Fprintln(s.out, "Conversation cleared")
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "model",
+		usage:       "[name]",
+		description: "Print the model, or switch to another one",
+		run: func(s *session, args string) error {
+			cfg := core.Config()
+			if cfg == nil {
+				return fmt.Errorf("no configuration loaded")
+			}
+
+			if args == "" {
+				switch cfg.Assistants.Plugin {
+				case "ollama":
+					fmt.Fprintln(s.out, cfg.Assistants.Ollama.Model)
+				case "openai":
+					fmt.Fprintln(s.out, cfg.Assistants.OpenAI.Model)
+				}
+				return nil
+			}
+
+			err := switchAssistant(s, func(cfg *config.Base) {
+				switch cfg.Assistants.Plugin {
+				case "ollama":
+					cfg.Assistants.Ollama.Model = args
+				case "openai":
+					cfg.Assistants.OpenAI.Model = args
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(s.out, "Switched to the model %s\n", args)
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "plugin",
+		usage:       "[name]",
+		description: "Print the assistant plugin, or switch to another one",
+		run: func(s *session, args string) error {
+			cfg := core.Config()
+			if cfg == nil {
+				return fmt.Errorf("no configuration loaded")
+			}
+
+			if args == "" {
+				fmt.Fprintf(s.out, "%s (available: %s)\n", cfg.Assistants.Plugin, strings.Join(assistants.Plugins(), ", "))
+				return nil
+			}
+
+			err := switchAssistant(s, func(cfg *config.Base) {
+				cfg.Assistants.Plugin = args
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(s.out, "Switched to the plugin %s\n", args)
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "tools",
+		usage:       "[name]",
+		description: "List the registered tools, or print the schema of one",
+		run: func(s *session, args string) error {
+			repository := tools.GetRepository()
+			if repository == nil {
+				return fmt.Errorf("no tools registered")
+			}
+
+			if args != "" {
+				schema, ok := repository.Schema(args)
+				if !ok {
+					return fmt.Errorf("unknown tool %q", args)
+				}
+
+				data, err := json.MarshalIndent(schema, "", "  ")
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintln(s.out, string(data))
+				return nil
+			}
+
+			names := make([]string, 0, len(repository.Tools))
+			for name := range repository.Tools {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			if len(names) == 0 {
+				fmt.Fprintln(s.out, "No tools registered")
+			}
+
+			for _, name := range names {
+				tool, _ := repository.Get(name)
+				fmt.Fprintf(s.out, "  %-16s %s\n", name, tool.Description())
+			}
+
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "system",
+		usage:       "[prompt]",
+		description: "Print the system prompt, or replace it until the end of the session",
+		run: func(s *session, args string) error {
+			assistant, err := defaultAssistant()
+			if err != nil {
+				return err
+			}
+
+			history := assistant.History()
+
+			if args == "" {
+				for _, message := range history {
+					if message.Role == "system" {
+						fmt.Fprintln(s.out, message.Content)
+					}
+				}
+				return nil
+			}
+
+			replaceSystemPrompt(assistant, args)
+			s.system = args
+			fmt.Fprintln(s.out, "System prompt replaced")
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "save",
+		usage:       "[file]",
+		description: "Save the conversation, in the sessions directory for a bare name",
+		run: func(s *session, args string) error {
+			assistant, err := defaultAssistant()
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(assistant.History(), "", "  ")
+			if err != nil {
+				return err
+			}
+
+			path := sessionPath(args)
+
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err != nil {
+				return err
+			}
+
+			err = os.WriteFile(path, data, 0600)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(s.out, "Conversation saved to %s\n", path)
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "load",
+		usage:       "<file>",
+		description: "Load a saved conversation, keeping the current system prompt",
+		run: func(s *session, args string) error {
+			if args == "" {
+				return fmt.Errorf("usage: /load <file>")
+			}
+
+			assistant, err := defaultAssistant()
+			if err != nil {
+				return err
+			}
+
+			path := sessionPath(args)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			var messages []assistants.Message
+			err = json.Unmarshal(data, &messages)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+
+			loaded := make([]assistants.Message, 0, len(messages))
+			for _, message := range assistant.History() {
+				if message.Role == "system" {
+					loaded = append(loaded, message)
+				}
+			}
+
+			for _, message := range messages {
+				if message.Role != "system" {
+					loaded = append(loaded, message)
+				}
+			}
+
+			assistant.SetHistory(loaded)
+			fmt.Fprintf(s.out, "Conversation loaded from %s, %d messages\n", path, len(loaded))
+			return nil
+		},
+	})
+
+	registerSlashCommand(slashCommand{
+		name:        "retry",
+		description: "Send the last message again, replacing its answer",
+		run: func(s *session, args string) error {
+			assistant, err := defaultAssistant()
+			if err != nil {
+				return err
+			}
+
+			history := assistant.History()
+
+			for i := len(history) - 1; i >= 0; i-- {
+				if history[i].Role != "user" {
+					continue
+				}
+
+				assistant.SetHistory(history[:i])
+				return s.send(history[i].Content)
+			}
+
+			return fmt.Errorf("no message to retry")
+		},
+	})
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/plugins/tools"
+	"github.com/spf13/cobra"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// toolsCmd groups the commands to inspect and run the tools.
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Inspect and run the tools",
+	Long:  `Inspect the tools available on this system and run them directly, without any model involved.`,
+}
+
+// toolsListCmd lists the tools available on this system.
+var toolsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the tools available on this system, with their enabled state",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := bootCore()
+		if err != nil {
+			return err
+		}
+
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "NAME\tENABLED\tDESCRIPTION")
+
+		for _, name := range tools.Names() {
+			tool, _ := tools.NewTool(cfg, name)
+			fmt.Fprintf(out, "%s\t%t\t%s\n", name, tools.IsEnabled(cfg, name), tool.Description())
+		}
+
+		return out.Flush()
+	},
+}
+
+// toolsSchemaCmd prints the schema of a tool.
+var toolsSchemaCmd = &cobra.Command{
+	Use:          "schema <name>",
+	Short:        "Print the JSON schema of a tool, as given to the model",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := bootCore()
+		if err != nil {
+			return err
+		}
+
+		repository, err := toolRepository(cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		schema, _ := repository.Schema(args[0])
+
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	},
+}
+
+// toolsRunCmd runs a tool without any model involved.
+var toolsRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a tool and print its response",
+	Long: `Run a tool without any model involved and print its response as JSON. The arguments are given
+with --arg key=value, repeated, or all at once with --json '{"key": "value"}'.
+
+With --summarize, the prompts of a "prompt" response are summarized by the default assistant, like
+during a conversation.`,
+	Example: `  pishia tools run browser --arg search="weather in Paris"
+  pishia tools run browser --json '{"url": "https://example.com"}' --summarize`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		arguments, err := toolArguments(cmd)
+		if err != nil {
+			return withExitCode(exitUsage, "%w", err)
+		}
+
+		cfg, err := bootCore()
+		if err != nil {
+			return withExitCode(exitConfig, "%w", err)
+		}
+
+		repository, err := toolRepository(cfg, name)
+		if err != nil {
+			return withExitCode(exitUsage, "%w", err)
+		}
+
+		if !tools.IsEnabled(cfg, name) {
+			log.Warnf("Tool %s is disabled in the configuration", name)
+		}
+
+		// The tools are registered, some of them look up the others.
+		tools.SetRepository(repository)
+		tool, _ := repository.Get(name)
+
+		err = tool.Setup()
+		if err != nil {
+			return withExitCode(exitUnavailable, "setting up the tool: %w", err)
+		}
+
+		userQuery, _ := cmd.Flags().GetString("query")
+		if search, ok := arguments["search"].(string); ok && userQuery == "" {
+			userQuery = search
+		}
+
+		response, err := tool.Run(arguments, userQuery)
+		if err != nil {
+			return withExitCode(exitRequest, "running the tool: %w", err)
+		}
+
+		summarize, _ := cmd.Flags().GetBool("summarize")
+		if summarize && response.Type == "prompt" {
+			response.Prompts, err = summarizePrompts(cmd.Context(), cfg, response.Prompts, userQuery)
+			if err != nil {
+				return withExitCode(exitRequest, "summarizing the response: %w", err)
+			}
+		}
+
+		data, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	},
+}
+
+// bootCore boots the core and returns its configuration.
+func bootCore() (*config.Base, error) {
+	err := core.Boot()
+	if err != nil {
+		return nil, fmt.Errorf("booting the core: %w", err)
+	}
+
+	return core.Config(), nil
+}
+
+// toolRepository returns a repository with only the tool, enabled or not.
+func toolRepository(cfg *config.Base, name string) (*tools.ToolRepository, error) {
+	tool, ok := tools.NewTool(cfg, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q, available tools: %s", name, strings.Join(tools.Names(), ", "))
+	}
+
+	repository := tools.NewToolRepository()
+	repository.Register(name, tool)
+	return repository, nil
+}
+
+// toolArguments parses the --json and --arg flags, --arg wins over --json.
+func toolArguments(cmd *cobra.Command) (map[string]interface{}, error) {
+	arguments := make(map[string]interface{})
+
+	if raw, _ := cmd.Flags().GetString("json"); raw != "" {
+		err := json.Unmarshal([]byte(raw), &arguments)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --json: %w", err)
+		}
+	}
+
+	pairs, _ := cmd.Flags().GetStringArray("arg")
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --arg %q, expected key=value", pair)
+		}
+
+		arguments[key] = value
+	}
+
+	return arguments, nil
+}
+
+// summarizePrompts summarizes the prompts of a tool response with the default assistant.
+func summarizePrompts(ctx context.Context, cfg *config.Base, prompts []string, userQuery string) ([]string, error) {
+	assistant := assistants.GetDefaultAssistant()
+	if assistant == nil {
+		return nil, fmt.Errorf("no assistant available")
+	}
+
+	completer, ok := assistant.(assistants.Completer)
+	if !ok {
+		return nil, fmt.Errorf("the %s assistant can't summarize", cfg.Assistants.Plugin)
+	}
+
+	err := assistant.Setup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return assistants.NewSummarizer(completer, cfg.Assistants.Summarization).Summarize(ctx, prompts, userQuery)
+}
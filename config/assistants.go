@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Assistants is the configuration of the assistants.
 type Assistants struct {
 	// Plugin is the plugin of the assistants.
@@ -8,6 +10,10 @@ type Assistants struct {
 	Ollama Ollama `yaml:"ollama,omitempty"`
 	// OpenAI is the configuration of the OpenAI assistant.
 	OpenAI OpenAI `yaml:"openai,omitempty"`
+	// Summarization is the configuration of the summarization of long tool outputs.
+	Summarization Summarization `yaml:"summarization,omitempty"`
+	// Generation are the parameters of the generation of the answers, for every plugin.
+	Generation Generation `yaml:"generation,omitempty"`
 }
 
 // Ollama is the configuration of the Ollama assistant.
@@ -16,6 +22,14 @@ type Ollama struct {
 	Model string `yaml:"model"`
 	// Endpoint is the endpoint of the Ollama.
 	Endpoint string `yaml:"endpoint,omitempty"`
+	// DialTimeout is the timeout to connect to the Ollama.
+	DialTimeout time.Duration `yaml:"dial_timeout,omitempty"`
+	// ResponseHeaderTimeout is the time to wait for the Ollama to start answering a request, which
+	// includes loading the model in memory.
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout,omitempty"`
+	// KeepAlive is how long the model stays in memory after a request, the default of the Ollama if
+	// it is zero and forever if it is negative.
+	KeepAlive time.Duration `yaml:"keep_alive,omitempty"`
 }
 
 // OpenAI is the configuration of the OpenAI assistant.
@@ -23,7 +37,36 @@ type OpenAI struct {
 	// Model is the model of the OpenAI.
 	Model string `yaml:"model"`
 	// APIKey is the API key of the OpenAI.
-	APIKey string `yaml:"api_key"`
+	APIKey string `yaml:"api_key" secret:"true"`
 	// Endpoint is the endpoint of the OpenAI.
 	Endpoint string `yaml:"endpoint,omitempty"`
 }
+
+// Summarization is the configuration of the summarization of long tool outputs.
+type Summarization struct {
+	// ChunkSize is the maximum number of tokens of each chunk sent to the model.
+	ChunkSize int `yaml:"chunk_size,omitempty"`
+	// Concurrency is the maximum number of chunks summarized at the same time.
+	Concurrency int `yaml:"concurrency,omitempty"`
+}
+
+// Generation are the parameters of the generation of the answers, the ones not set keep the values of
+// the model. The parameters without an equivalent in the API of a plugin are ignored by it.
+type Generation struct {
+	// Temperature is the randomness of the answers, from 0 for the most likely one to 2.
+	Temperature *float64 `yaml:"temperature,omitempty"`
+	// TopP keeps the most likely tokens up to this cumulative probability, from 0 to 1.
+	TopP *float64 `yaml:"top_p,omitempty"`
+	// MaxTokens is the maximum number of tokens of an answer.
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+	// NumCtx is the size of the context window in tokens, only for the Ollama.
+	NumCtx int `yaml:"num_ctx,omitempty"`
+	// Seed makes the answers reproducible for the same prompt.
+	Seed *int `yaml:"seed,omitempty"`
+	// Stop are the sequences that end an answer.
+	Stop []string `yaml:"stop,omitempty"`
+	// RepeatPenalty penalizes the repeated tokens, 1 disables it, only for the Ollama.
+	RepeatPenalty *float64 `yaml:"repeat_penalty,omitempty"`
+	// Format is the format of the answers, json to answer with a JSON object, text by default.
+	Format string `yaml:"format,omitempty"`
+}
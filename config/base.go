@@ -1,8 +1,68 @@
 package config
 
+import "gopkg.in/yaml.v3"
+
+// source is a file the configuration was loaded from, kept to report line numbers.
+type source struct {
+	// path is the path of the file.
+	path string
+	// document is the YAML document of the file.
+	document *yaml.Node
+}
+
+// Base is the configuration of Pishia.
 type Base struct {
+	// Version is the version of the configuration schema, older configurations are migrated when loaded.
+	Version int `yaml:"version"`
 	// Assistants is the configuration of the assistants.
 	Assistants Assistants `yaml:"assistants"`
 	// Tool is the configuration of the tool.
 	Tool Tool `yaml:"tool"`
+	// Prompts is the configuration of the prompts.
+	Prompts Prompts `yaml:"prompts,omitempty"`
+	// Log is the configuration of the logs.
+	Log Log `yaml:"log,omitempty"`
+	// Server is the configuration of the HTTP API.
+	Server Server `yaml:"server,omitempty"`
+	// Profile is the profile applied when none is selected with --profile or PISHIA_PROFILE.
+	Profile string `yaml:"profile,omitempty"`
+	// Profiles are the named profiles, each one overrides part of the configuration.
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// TrustedProjects are the directories whose .pishia.yaml can set any key. The project files of the
+	// other directories can only change the models, the prompts, the generation and the tool set.
+	TrustedProjects []string `yaml:"trusted_projects,omitempty"`
+
+	// sources are the files the configuration was loaded from, in the order they were applied.
+	sources []source
+	// problems are the problems found while decoding the document.
+	problems []Problem
+	// activeProfile is the profile applied to the configuration.
+	activeProfile string
+}
+
+// Files returns the files the configuration was loaded from, in the order they were applied.
+func (b *Base) Files() []string {
+	files := make([]string, 0, len(b.sources))
+
+	for _, source := range b.sources {
+		files = append(files, source.path)
+	}
+
+	return files
+}
+
+// locate returns the file and line of the key at the dotted path. The last file setting the key wins,
+// if no file sets it the closest parent is used.
+func (b *Base) locate(path string) (string, int) {
+	file, line, depth := "", 0, -1
+
+	for i := len(b.sources) - 1; i >= 0; i-- {
+		sourceLine, sourceDepth := lineOf(b.sources[i].document, path)
+
+		if sourceDepth > depth {
+			file, line, depth = b.sources[i].path, sourceLine, sourceDepth
+		}
+	}
+
+	return file, line
 }
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// secretMask replaces the secrets when the configuration is shown.
+	secretMask = "********"
+	// defaultIndent is the indentation of the new configuration files.
+	defaultIndent = 4
+)
+
+// ReadDocument reads a YAML file as a node tree, keeping comments and the order of the keys.
+func ReadDocument(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if document.Kind == 0 {
+		document.Kind = yaml.DocumentNode
+	}
+
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	return &document, nil
+}
+
+// EncodeDocument encodes a node tree with the indentation of the new configuration files.
+func EncodeDocument(document *yaml.Node) ([]byte, error) {
+	return encodeDocument(document, defaultIndent)
+}
+
+// encodeDocument encodes a node tree with the indentation.
+func encodeDocument(document *yaml.Node, indent int) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(indent)
+
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// WriteDocument writes a node tree to a YAML file, keeping the indentation of the file it replaces.
+func WriteDocument(path string, document *yaml.Node) error {
+	indent := defaultIndent
+	if data, err := os.ReadFile(path); err == nil {
+		indent = detectIndent(data)
+	}
+
+	data, err := encodeDocument(document, indent)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// detectIndent returns the indentation of a YAML file: the one of its first indented line, or the
+// default one if no line is indented.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if indent := len(line) - len(trimmed); indent >= 2 && indent <= 8 {
+			return indent
+		}
+	}
+
+	return defaultIndent
+}
+
+// GetNode gets the node of the key at the dotted path, like assistants.ollama.model.
+func GetNode(document *yaml.Node, path string) (*yaml.Node, bool) {
+	node := document
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, key := range splitPath(path) {
+		node = mappingValue(node, key)
+		if node == nil {
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// SetValue sets the value of the key at the dotted path, creating the missing parents. The value is
+// parsed as YAML, so numbers and booleans keep their type.
+func SetValue(document *yaml.Node, path string, value string) error {
+	if !KnownKey(path) {
+		return fmt.Errorf("unknown key %q", path)
+	}
+
+	var parsed yaml.Node
+
+	err := yaml.Unmarshal([]byte(value), &parsed)
+	if err != nil {
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+
+	node := document
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			node.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+		}
+		node = node.Content[0]
+	}
+
+	keys := splitPath(path)
+
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", strings.Join(keys[:i], "."))
+		}
+
+		child := mappingValue(node, key)
+
+		if i == len(keys)-1 {
+			if child != nil {
+				// Keep the comments of the replaced value.
+				valueNode.HeadComment = child.HeadComment
+				valueNode.LineComment = child.LineComment
+				valueNode.FootComment = child.FootComment
+				*child = *valueNode
+				return nil
+			}
+
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+			return nil
+		}
+
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+
+		node = child
+	}
+
+	return nil
+}
+
+// KnownKey checks if the dotted path is a key of the configuration.
+func KnownKey(path string) bool {
+	t := reflect.TypeOf(Base{})
+
+	for _, key := range splitPath(path) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		switch {
+		case t == yamlNodeType:
+			return true
+		case t.Kind() == reflect.Struct:
+			field, ok := yamlFields(t)[key]
+			if !ok {
+				return false
+			}
+			t = field.Type
+		case t.Kind() == reflect.Map:
+			t = t.Elem()
+		default:
+			return false
+		}
+	}
+
+	return path != ""
+}
+
+// MaskSecrets returns a copy of the configuration where the secrets are masked, the secret references
+// are kept.
+func MaskSecrets(base *Base) *Base {
+	masked := *base
+
+	walkFields(reflect.ValueOf(&masked).Elem(), "", func(field reflect.Value, path string, structField reflect.StructField) {
+		if structField.Tag.Get("secret") != "true" || field.Kind() != reflect.String || field.String() == "" {
+			return
+		}
+
+		// A reference tells where the secret is, not the secret itself.
+		if IsSecretReference(field.String()) {
+			return
+		}
+
+		field.SetString(MaskSecret(field.String()))
+	})
+
+	// The profiles are raw nodes, their secrets are found by path.
+	if base.Profiles != nil {
+		masked.Profiles = make(map[string]Profile, len(base.Profiles))
+
+		for name, profile := range base.Profiles {
+			masked.Profiles[name] = maskProfile(profile)
+		}
+	}
+
+	return &masked
+}
+
+// maskProfile returns a copy of the profile where the secrets of the overrides are masked.
+func maskProfile(profile Profile) Profile {
+	for key, node := range profile.nodes() {
+		*node = *cloneNode(node)
+
+		for _, path := range secretPaths() {
+			rest, ok := strings.CutPrefix(path, key+".")
+			if !ok {
+				continue
+			}
+
+			secret, ok := GetNode(node, rest)
+			if ok && secret.Kind == yaml.ScalarNode && !IsSecretReference(secret.Value) {
+				secret.Value = MaskSecret(secret.Value)
+			}
+		}
+	}
+
+	return profile
+}
+
+// cloneNode returns a deep copy of the node.
+func cloneNode(node *yaml.Node) *yaml.Node {
+	clone := *node
+	clone.Content = make([]*yaml.Node, len(node.Content))
+
+	for i, content := range node.Content {
+		clone.Content[i] = cloneNode(content)
+	}
+
+	return &clone
+}
+
+// MaskSecret masks a secret, only the last characters of long secrets are kept to tell them apart.
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	if len(secret) < 16 {
+		return secretMask
+	}
+
+	return secretMask + secret[len(secret)-4:]
+}
+
+// IsSecretKey checks if the key at the dotted path holds a secret, in the configuration or in a
+// profile, like profiles.work.assistants.openai.api_key.
+func IsSecretKey(path string) bool {
+	if keys := strings.SplitN(path, ".", 3); len(keys) == 3 && keys[0] == "profiles" {
+		path = keys[2]
+	}
+
+	return slices.Contains(secretPaths(), path)
+}
+
+// secretPaths returns the dotted paths of the secrets of the configuration.
+func secretPaths() []string {
+	paths := make([]string, 0)
+
+	walkFields(reflect.ValueOf(&Base{}).Elem(), "", func(field reflect.Value, path string, structField reflect.StructField) {
+		if structField.Tag.Get("secret") == "true" {
+			paths = append(paths, path)
+		}
+	})
+
+	return paths
+}
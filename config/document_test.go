@@ -0,0 +1,71 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const profileSecrets = `assistants:
+    openai:
+        api_key: sk-user-secret-that-is-long
+profiles:
+    work:
+        assistants:
+            openai:
+                api_key: sk-work-secret-that-is-long
+    vault:
+        assistants:
+            openai:
+                api_key: "cmd:pass show openai"
+`
+
+func TestMaskSecretsInProfiles(t *testing.T) {
+	base, err := Parse([]byte(profileSecrets), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := yaml.Marshal(MaskSecrets(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"sk-user-secret-that-is-long", "sk-work-secret-that-is-long"} {
+		if strings.Contains(string(output), secret) {
+			t.Errorf("the masked configuration contains %q:\n%s", secret, output)
+		}
+	}
+
+	if !strings.Contains(string(output), "cmd:pass show openai") {
+		t.Errorf("the masked configuration doesn't contain the reference:\n%s", output)
+	}
+
+	// The configuration itself is left untouched.
+	work := base.Profiles["work"]
+	node, _ := GetNode(&work.Assistants, "openai.api_key")
+	if node.Value != "sk-work-secret-that-is-long" {
+		t.Errorf("MaskSecrets changed the profile of the configuration to %q", node.Value)
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	tests := []struct {
+		path   string
+		secret bool
+	}{
+		{"assistants.openai.api_key", true},
+		{"server.token", true},
+		{"profiles.work.assistants.openai.api_key", true},
+		{"assistants.openai.model", false},
+		{"profiles.work.assistants.openai.model", false},
+		{"profiles.work", false},
+	}
+
+	for _, test := range tests {
+		if got := IsSecretKey(test.path); got != test.secret {
+			t.Errorf("IsSecretKey(%q) = %v, want %v", test.path, got, test.secret)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix is the prefix of the environment variables that override the configuration.
+	EnvPrefix = "PISHIA_"
+	// fileSecretPrefix is the prefix of the secrets read from a file.
+	fileSecretPrefix = "file:"
+	// cmdSecretPrefix is the prefix of the secrets read from the output of a command.
+	cmdSecretPrefix = "cmd:"
+)
+
+var (
+	// envReference matches ${VAR} and ${VAR:-default}.
+	envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+	// durationType is the type of the durations, decoded from strings like 10s.
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	// secretCommands are the outputs of the secret commands already run, by command.
+	secretCommands = make(map[string]string)
+	// secretCommandsMutex guards secretCommands, a command is run only once.
+	secretCommandsMutex sync.Mutex
+)
+
+// interpolateEnv replaces ${VAR} and ${VAR:-default} with the value of the environment variable in
+// every scalar value of the document. Keys are left untouched.
+func interpolateEnv(node *yaml.Node, file string, problems *[]Problem) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, content := range node.Content {
+			interpolateEnv(content, file, problems)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			interpolateEnv(node.Content[i+1], file, problems)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+
+		node.Value = envReference.ReplaceAllStringFunc(node.Value, func(reference string) string {
+			matches := envReference.FindStringSubmatch(reference)
+			name, fallback := matches[1], matches[2]
+
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+
+			if strings.Contains(reference, ":-") {
+				return fallback
+			}
+
+			*problems = append(*problems, Problem{
+				File:    file,
+				Line:    node.Line,
+				Message: fmt.Sprintf("environment variable %s is not set", name),
+			})
+			return ""
+		})
+
+		// The value may be a number or a boolean once interpolated, let the decoder resolve it again.
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+}
+
+// applyEnvOverrides sets every field that has a PISHIA_ environment variable, like
+// PISHIA_ASSISTANTS_OPENAI_API_KEY for assistants.openai.api_key.
+func applyEnvOverrides(base *Base) {
+	walkFields(reflect.ValueOf(base).Elem(), "", func(field reflect.Value, path string, structField reflect.StructField) {
+		name := EnvName(path)
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		// The environment is not a trusted layer, a reference could run a command or read any file.
+		if structField.Tag.Get("secret") == "true" && IsSecretReference(value) {
+			base.problems = append(base.problems, Problem{
+				File:    "environment",
+				Path:    path,
+				Message: fmt.Sprintf("%s can't reference a secret, set the secret itself", name),
+			})
+			return
+		}
+
+		if err := setFromString(field, value); err != nil {
+			base.problems = append(base.problems, Problem{
+				File:    "environment",
+				Path:    path,
+				Message: fmt.Sprintf("invalid value of %s: %v", name, err),
+			})
+		}
+	})
+}
+
+// ResolveSecret resolves a secret reference. file:path reads the file and cmd:command runs the command,
+// in both cases the surrounding whitespace is trimmed. Other values are returned as they are. The
+// secrets are resolved when they are used, not when the configuration is loaded, and the output of
+// the commands is kept for the next calls.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, fileSecretPrefix):
+		path := expandHome(strings.TrimSpace(strings.TrimPrefix(value, fileSecretPrefix)))
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret file: %w", err)
+		}
+
+		return strings.TrimSpace(string(content)), nil
+	case strings.HasPrefix(value, cmdSecretPrefix):
+		command := strings.TrimSpace(strings.TrimPrefix(value, cmdSecretPrefix))
+
+		secretCommandsMutex.Lock()
+		defer secretCommandsMutex.Unlock()
+
+		if secret, ok := secretCommands[command]; ok {
+			return secret, nil
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Stderr = os.Stderr
+
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("running secret command %q: %w", command, err)
+		}
+
+		secret := strings.TrimSpace(string(output))
+		secretCommands[command] = secret
+
+		return secret, nil
+	}
+
+	return value, nil
+}
+
+// IsSecretReference checks if the value references a secret instead of containing it.
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, fileSecretPrefix) || strings.HasPrefix(value, cmdSecretPrefix)
+}
+
+// EnvName returns the name of the environment variable that overrides the key at the dotted path.
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+}
+
+// walkFields calls fn for every leaf field of the struct, with its dotted YAML path. Maps and raw YAML
+// nodes are not walked.
+func walkFields(value reflect.Value, path string, fn func(field reflect.Value, path string, structField reflect.StructField)) {
+	t := value.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		if !structField.IsExported() {
+			continue
+		}
+
+		tag := structField.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = strings.ToLower(structField.Name)
+		}
+
+		field := value.Field(i)
+
+		if structField.Type.Kind() == reflect.Struct && structField.Type != yamlNodeType {
+			if strings.Contains(options, "inline") {
+				walkFields(field, path, fn)
+			} else {
+				walkFields(field, joinPath(path, name), fn)
+			}
+			continue
+		}
+
+		if structField.Type.Kind() == reflect.Map || structField.Type == yamlNodeType {
+			continue
+		}
+
+		fn(field, joinPath(path, name), structField)
+	}
+}
+
+// setFromString sets the field from its string representation.
+func setFromString(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(items)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setFromString(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// expandHome replaces a leading ~ with the home directory of the user.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretsResolvedWhenUsed(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	reference := "cmd:echo run >> " + counter + "; echo sk-from-command"
+
+	base, err := Parse([]byte("assistants:\n    openai:\n        api_key: \""+reference+"\"\n"), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(counter); err == nil {
+		t.Fatal("the secret command was run while loading the configuration")
+	}
+
+	if base.Assistants.OpenAI.APIKey != reference {
+		t.Errorf("got api_key %q, want the reference", base.Assistants.OpenAI.APIKey)
+	}
+
+	for i := 0; i < 2; i++ {
+		secret, err := ResolveSecret(base.Assistants.OpenAI.APIKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if secret != "sk-from-command" {
+			t.Errorf("got secret %q, want %q", secret, "sk-from-command")
+		}
+	}
+
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("the secret command was run %d times, want once", n)
+	}
+}
+
+func TestEnvSecretReference(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "executed")
+	t.Setenv("PISHIA_ASSISTANTS_OPENAI_API_KEY", "cmd:touch "+marker)
+
+	base, err := Parse([]byte("assistants:\n    openai:\n        api_key: user-key\n"), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base.Assistants.OpenAI.APIKey != "user-key" {
+		t.Errorf("got api_key %q, want the reference of the environment to be ignored", base.Assistants.OpenAI.APIKey)
+	}
+
+	if len(base.problems) != 1 || base.problems[0].Path != "assistants.openai.api_key" {
+		t.Errorf("got problems %v, want one about assistants.openai.api_key", base.problems)
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the secret command of the environment was run")
+	}
+}
+
+func TestMaskSecretsKeepsReferences(t *testing.T) {
+	base := &Base{}
+	base.Assistants.OpenAI.APIKey = "cmd:pass show openai"
+	base.Server.Token = "a-token-that-is-long-enough"
+
+	masked := MaskSecrets(base)
+
+	if masked.Assistants.OpenAI.APIKey != "cmd:pass show openai" {
+		t.Errorf("got api_key %q, want the reference", masked.Assistants.OpenAI.APIKey)
+	}
+
+	if masked.Server.Token != MaskSecret("a-token-that-is-long-enough") {
+		t.Errorf("got token %q, want it masked", masked.Server.Token)
+	}
+}
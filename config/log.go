@@ -0,0 +1,16 @@
+package config
+
+// Log is the configuration of the logs.
+type Log struct {
+	// Level is the minimum level of the logs: trace, debug, info, warn, error, fatal or panic.
+	Level string `yaml:"level,omitempty"`
+	// Format is the format of the logs: text or json.
+	Format string `yaml:"format,omitempty"`
+	// File is the file the logs are written to instead of the standard error, relative to the state
+	// directory. It is rotated when it grows too big.
+	File string `yaml:"file,omitempty"`
+	// MaxSize is the size in megabytes of the log file before it is rotated.
+	MaxSize int `yaml:"max_size,omitempty"`
+	// MaxBackups is the number of rotated log files kept.
+	MaxBackups int `yaml:"max_backups,omitempty"`
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the configuration schema.
+const CurrentVersion = 1
+
+var (
+	// migrations are the registered migrations, indexed by the version they upgrade from.
+	migrations = make(map[int]Migration)
+)
+
+// Migration upgrades a configuration document from a version to the next one.
+type Migration struct {
+	// From is the version the migration upgrades from, the document is at From+1 after it.
+	From int
+	// Description describes the changes of the migration.
+	Description string
+	// Migrate changes the root mapping of the document in place.
+	Migrate func(root *yaml.Node) error
+}
+
+// RegisterMigration registers a migration, there can only be one migration from each version.
+func RegisterMigration(migration Migration) {
+	if _, ok := migrations[migration.From]; ok {
+		panic(fmt.Sprintf("migration from version %d already registered", migration.From))
+	}
+
+	migrations[migration.From] = migration
+}
+
+// DocumentVersion returns the version of a configuration document, 0 if it has no version.
+func DocumentVersion(document *yaml.Node) (int, error) {
+	node, ok := GetNode(document, "version")
+	if !ok {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(node.Value)
+	if err != nil {
+		return 0, fmt.Errorf("line %d: invalid version %q", node.Line, node.Value)
+	}
+
+	return version, nil
+}
+
+// Migrate upgrades the document to the current version in place, returning the migrations applied.
+func Migrate(document *yaml.Node) ([]Migration, error) {
+	version, err := DocumentVersion(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("configuration version %d is newer than the supported version %d, please upgrade Pishia", version, CurrentVersion)
+	}
+
+	applied := make([]Migration, 0)
+
+	for ; version < CurrentVersion; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from version %d", version)
+		}
+
+		root := document
+		if root.Kind == yaml.DocumentNode {
+			root = root.Content[0]
+		}
+
+		err := migration.Migrate(root)
+		if err != nil {
+			return nil, fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+
+		err = SetValue(document, "version", strconv.Itoa(version+1))
+		if err != nil {
+			return nil, err
+		}
+
+		applied = append(applied, migration)
+	}
+
+	moveVersionFirst(document)
+
+	return applied, nil
+}
+
+// MigrateFile upgrades the configuration file to the current version, keeping its indentation. The old
+// file is copied to a backup before being replaced, the path of the backup is returned. With dryRun, nothing is written
+// and the migrated content is returned instead.
+func MigrateFile(path string, dryRun bool) (applied []Migration, migrated []byte, backup string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	document, err := ReadDocument(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	applied, err = Migrate(document)
+	if err != nil || len(applied) == 0 {
+		return applied, data, "", err
+	}
+
+	migrated, err = encodeDocument(document, detectIndent(data))
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if dryRun {
+		return applied, migrated, "", nil
+	}
+
+	backup = fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405"))
+
+	err = os.WriteFile(backup, data, 0600)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	err = writeFile(path, migrated)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return applied, migrated, backup, nil
+}
+
+// migrateLayer migrates a configuration document in memory when its version is older than the
+// current one, returning the problems found.
+func migrateLayer(document *yaml.Node, path string) []Problem {
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	version, err := DocumentVersion(document)
+	if err != nil || version >= CurrentVersion {
+		// Invalid and newer versions are reported when the configuration is validated.
+		return nil
+	}
+
+	_, err = Migrate(document)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+
+	return nil
+}
+
+// moveVersionFirst moves the version key to the top of the document.
+func moveVersionFirst(document *yaml.Node) {
+	root := document
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "version" || i == 0 {
+			continue
+		}
+
+		key, value := root.Content[i], root.Content[i+1]
+		content := append([]*yaml.Node{key, value}, root.Content[:i]...)
+		root.Content = append(content, root.Content[i+2:]...)
+		return
+	}
+}
+
+func init() {
+	RegisterMigration(Migration{
+		From:        0,
+		Description: "replace the <api_key> placeholder of the OpenAI assistant with ${OPENAI_API_KEY:-}",
+		Migrate: func(root *yaml.Node) error {
+			apiKey, ok := GetNode(root, "assistants.openai.api_key")
+			if ok && placeholder.MatchString(apiKey.Value) {
+				apiKey.Value = "${OPENAI_API_KEY:-}"
+				apiKey.Style = yaml.DoubleQuotedStyle
+			}
+
+			return nil
+		},
+	})
+}
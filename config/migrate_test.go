@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// versionZero is a configuration file of version 0, indented with 2 spaces.
+const versionZero = `# My configuration
+assistants:
+  plugin: ollama
+  openai:
+    api_key: <api_key>
+`
+
+func TestLoadDoesNotRewriteConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("PISHIA_HOME", home)
+	t.Setenv("PISHIA_CONFIG", "")
+
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte(versionZero), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := GetCurrentConfigurations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base.Version != CurrentVersion {
+		t.Errorf("got version %d, want the configuration migrated in memory to %d", base.Version, CurrentVersion)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != versionZero {
+		t.Errorf("loading the configuration rewrote the file:\n%s", data)
+	}
+
+	backups, _ := filepath.Glob(path + ".*.bak")
+	if len(backups) > 0 {
+		t.Errorf("loading the configuration created the backups %v", backups)
+	}
+}
+
+func TestLoadMigratesWithEnvironment(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-real")
+
+	base, err := Parse([]byte(versionZero), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base.Assistants.OpenAI.APIKey != "sk-real" {
+		t.Errorf("got API key %q, want the migrated reference interpolated to %q", base.Assistants.OpenAI.APIKey, "sk-real")
+	}
+}
+
+func TestValidateRejectsEnvReference(t *testing.T) {
+	base := DefaultConfig()
+	base.Assistants.Plugin = "openai"
+	base.Assistants.OpenAI.APIKey = "${OPENAI_API_KEY:-}"
+
+	err := Validate(base, Names{Assistants: []string{"ollama", "openai"}})
+	if err == nil || !strings.Contains(err.Error(), "assistants.openai.api_key") {
+		t.Errorf("got error %v, want the unresolved reference of assistants.openai.api_key", err)
+	}
+}
+
+func TestMigrateFileKeepsIndentation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(versionZero), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, migrated, backup, err := MigrateFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != 1 || backup == "" {
+		t.Fatalf("got %d migrations and backup %q, want one migration and a backup", len(applied), backup)
+	}
+
+	want := "version: 1\n# My configuration\nassistants:\n  plugin: ollama\n  openai:\n    api_key: \"${OPENAI_API_KEY:-}\"\n"
+	if string(migrated) != want {
+		t.Errorf("got migrated file:\n%s\nwant:\n%s", migrated, want)
+	}
+}
+
+func TestDetectIndent(t *testing.T) {
+	tests := []struct {
+		data   string
+		indent int
+	}{
+		{"a:\n  b: 1\n", 2},
+		{"a:\n    b: 1\n", 4},
+		{"# comment\n\na:\n   b: 1\n", 3},
+		{"a: 1\nb: 2\n", defaultIndent},
+		{"", defaultIndent},
+	}
+
+	for _, test := range tests {
+		if got := detectIndent([]byte(test.data)); got != test.indent {
+			t.Errorf("detectIndent(%q) = %d, want %d", test.data, got, test.indent)
+		}
+	}
+}
+
+func TestWriteDocumentKeepsIndentation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("assistants:\n  plugin: ollama\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	document, err := ReadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetValue(document, "assistants.ollama.model", "llama3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteDocument(path, document); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), "\n  ollama:\n    model: llama3\n") {
+		t.Errorf("the indentation of the file was not kept:\n%s", data)
+	}
+}
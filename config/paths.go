@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kirsle/configdir"
+)
+
+const (
+	// appName is the name of the directories of Pishia.
+	appName = "pishia"
+	// projectConfigName is the name of the configuration file of a project.
+	projectConfigName = ".pishia.yaml"
+)
+
+var (
+	// configFile is the file given with --config, it is applied on top of the other files.
+	configFile string
+
+	// ErrConfigNotFound is returned when the file given with --config doesn't exist.
+	ErrConfigNotFound = errors.New("configuration file not found")
+)
+
+// SetConfigFile sets the configuration file given with --config. It is applied on top of the other
+// configuration files, and it is the one changed by the config commands.
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// ConfigPath returns the path of the configuration file changed by the config commands: the file given
+// with --config, then $PISHIA_CONFIG, then config.yaml in $PISHIA_HOME or the user configuration directory.
+func ConfigPath() string {
+	if configFile != "" {
+		return configFile
+	}
+
+	return UserConfigPath()
+}
+
+// UserConfigPath returns the path of the configuration file of the user: $PISHIA_CONFIG, then
+// config.yaml in $PISHIA_HOME or the user configuration directory.
+func UserConfigPath() string {
+	if path := os.Getenv("PISHIA_CONFIG"); path != "" {
+		return path
+	}
+
+	if home := os.Getenv("PISHIA_HOME"); home != "" {
+		return filepath.Join(home, "config.yaml")
+	}
+
+	return filepath.Join(configdir.LocalConfig(appName), "config.yaml")
+}
+
+// SystemConfigPath returns the path of the configuration file shared by all the users.
+func SystemConfigPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), appName, "config.yaml")
+	}
+
+	return filepath.Join("/etc", appName, "config.yaml")
+}
+
+// ProjectConfigPath returns the path of the .pishia.yaml file of the current directory or its closest
+// parent, empty if there is none.
+func ProjectConfigPath() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ConfigFiles returns the configuration files in the order they are applied: the system file, the
+// user file and the project file when they exist, and the file given with --config even if it
+// doesn't, so that loading it fails.
+func ConfigFiles() []string {
+	candidates := []string{SystemConfigPath(), UserConfigPath(), ProjectConfigPath(), configFile}
+	files := make([]string, 0, len(candidates))
+	seen := make(map[string]bool)
+
+	for _, path := range candidates {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if _, err := os.Stat(path); err == nil || path == configFile {
+			files = append(files, path)
+		}
+	}
+
+	return files
+}
+
+// DataDir returns the directory of the data of Pishia, like the saved sessions and the history.
+func DataDir() string {
+	return appDir("data", "XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// CacheDir returns the directory of the caches of Pishia.
+func CacheDir() string {
+	if home := os.Getenv("PISHIA_HOME"); home != "" {
+		return filepath.Join(home, "cache")
+	}
+
+	return configdir.LocalCache(appName)
+}
+
+// StateDir returns the directory of the state of Pishia, like the logs.
+func StateDir() string {
+	return appDir("state", "XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// appDir returns a directory of Pishia: a subdirectory of $PISHIA_HOME, then the XDG directory, then
+// the default of the system.
+func appDir(name string, xdgVariable string, xdgDefault string) string {
+	if home := os.Getenv("PISHIA_HOME"); home != "" {
+		return filepath.Join(home, name)
+	}
+
+	if dir := os.Getenv(xdgVariable); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), appName, name)
+	case "darwin":
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, "Library", "Application Support", appName, name)
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, xdgDefault, appName)
+}
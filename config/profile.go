@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// selectedProfile is the profile selected with the --profile flag, it wins over PISHIA_PROFILE and the profile key.
+	selectedProfile string
+)
+
+// Profile is a named set of overrides of the configuration. Only the keys present in the profile
+// override the configuration, the rest is kept.
+type Profile struct {
+	// Inherits is the name of the profile this profile is based on.
+	Inherits string `yaml:"inherits,omitempty"`
+	// Assistants overrides the configuration of the assistants.
+	Assistants yaml.Node `yaml:"assistants,omitempty"`
+	// Tool overrides the configuration of the tools.
+	Tool yaml.Node `yaml:"tool,omitempty"`
+	// Prompts overrides the prompts.
+	Prompts yaml.Node `yaml:"prompts,omitempty"`
+}
+
+// nodes returns the overrides of the profile, by the key of the configuration they override.
+func (p *Profile) nodes() map[string]*yaml.Node {
+	return map[string]*yaml.Node{
+		"assistants": &p.Assistants,
+		"tool":       &p.Tool,
+		"prompts":    &p.Prompts,
+	}
+}
+
+// SetProfile selects the profile to apply when the configuration is loaded.
+func SetProfile(name string) {
+	selectedProfile = name
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (b *Base) ProfileNames() []string {
+	names := make([]string, 0, len(b.Profiles))
+
+	for name := range b.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ActiveProfile returns the name of the profile applied to the configuration, empty if none.
+func (b *Base) ActiveProfile() string {
+	return b.activeProfile
+}
+
+// profileName returns the name of the profile to apply: the --profile flag, then PISHIA_PROFILE, then
+// the profile key of the configuration.
+func profileName(base *Base) string {
+	if selectedProfile != "" {
+		return selectedProfile
+	}
+
+	if name := os.Getenv(EnvName("profile")); name != "" {
+		return name
+	}
+
+	return base.Profile
+}
+
+// applyProfile applies the profile and the profiles it inherits from, from the base profile to the
+// selected one.
+func applyProfile(base *Base, name string) {
+	if name == "" {
+		return
+	}
+
+	chain, err := profileChain(base, name)
+	if err != nil {
+		file, line := base.locate("profiles." + name)
+
+		base.problems = append(base.problems, Problem{
+			File:    file,
+			Line:    line,
+			Path:    "profiles." + name,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	for _, profileName := range chain {
+		profile := base.Profiles[profileName]
+		file, _ := base.locate("profiles." + profileName)
+
+		overrides := []struct {
+			node   *yaml.Node
+			target interface{}
+		}{
+			{&profile.Assistants, &base.Assistants},
+			{&profile.Tool, &base.Tool},
+			{&profile.Prompts, &base.Prompts},
+		}
+
+		for _, override := range overrides {
+			if override.node.Kind == 0 {
+				continue
+			}
+
+			err := override.node.Decode(override.target)
+			if problems, ok := typeErrorProblems(file, err); ok {
+				base.problems = append(base.problems, problems...)
+			} else if err != nil {
+				base.problems = append(base.problems, Problem{
+					File:    file,
+					Line:    override.node.Line,
+					Path:    "profiles." + profileName,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	base.activeProfile = name
+}
+
+// profileChain returns the profile and the profiles it inherits from, starting with the base one.
+func profileChain(base *Base, name string) ([]string, error) {
+	chain := make([]string, 0)
+	visited := make(map[string]bool)
+
+	for current := name; current != ""; current = base.Profiles[current].Inherits {
+		if _, ok := base.Profiles[current]; !ok {
+			return nil, fmt.Errorf("unknown profile %q, available profiles: %s", current, strings.Join(base.ProfileNames(), ", "))
+		}
+
+		if visited[current] {
+			return nil, fmt.Errorf("profile %q inherits from itself", current)
+		}
+
+		visited[current] = true
+		chain = append([]string{current}, chain...)
+	}
+
+	return chain, nil
+}
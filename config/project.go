@@ -0,0 +1,156 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// projectKeys are the keys a project file can set when its directory is not trusted. They only change
+// how the answers are generated: no secret, endpoint, network allowlist or command can be set.
+var projectKeys = []string{
+	"version",
+	"profile",
+	"prompts",
+	"assistants.plugin",
+	"assistants.ollama.model",
+	"assistants.openai.model",
+	"assistants.generation",
+	"assistants.summarization",
+	"tool.set",
+	"tool.sets",
+}
+
+// trustedProjects returns the directories whose project file is applied without restrictions, listed in
+// trusted_projects of the layers. The project file itself can't trust its directory.
+func trustedProjects(layers []Layer, project string) []string {
+	dirs := make([]string, 0)
+
+	for _, layer := range layers {
+		if layer.Path == project {
+			continue
+		}
+
+		var trusted struct {
+			TrustedProjects []string `yaml:"trusted_projects"`
+		}
+
+		// The errors are reported when the layer is decoded.
+		if yaml.Unmarshal(layer.Data, &trusted) == nil {
+			dirs = append(dirs, trusted.TrustedProjects...)
+		}
+	}
+
+	return dirs
+}
+
+// isTrustedProject checks if the directory of the project file is one of the trusted directories.
+func isTrustedProject(path string, trusted []string) bool {
+	dir := canonicalPath(filepath.Dir(path))
+
+	for _, trustedDir := range trusted {
+		if canonicalPath(expandHome(trustedDir)) == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canonicalPath returns the absolute path with the symbolic links resolved, or the cleaned path if it
+// can't be resolved.
+func canonicalPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+
+	return abs
+}
+
+// restrictDocument removes the keys that are not in projectKeys from the document of an untrusted
+// project file, warning about the removed ones.
+func restrictDocument(document *yaml.Node, path string) {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+
+	removed := restrictMapping(document.Content[0], "")
+	if len(removed) == 0 {
+		return
+	}
+
+	log.Warnf("Ignoring %s in %s: the project is not trusted, add %s to trusted_projects in %s to apply them",
+		strings.Join(removed, ", "), path, filepath.Dir(path), UserConfigPath())
+}
+
+// restrictMapping removes the keys of the mapping at path that are not in projectKeys, returning their
+// paths.
+func restrictMapping(node *yaml.Node, path string) []string {
+	removed := make([]string, 0)
+	content := make([]*yaml.Node, 0, len(node.Content))
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := joinPath(path, key.Value)
+
+		switch {
+		case isProjectKey(keyPath):
+			content = append(content, key, value)
+		case isProjectKeyParent(keyPath) && value.Kind == yaml.MappingNode:
+			removed = append(removed, restrictMapping(value, keyPath)...)
+			content = append(content, key, value)
+		default:
+			removed = append(removed, keyPath)
+		}
+	}
+
+	node.Content = content
+	return removed
+}
+
+// isProjectKey checks if the key at the dotted path is in projectKeys or below one of them.
+func isProjectKey(path string) bool {
+	for _, key := range projectKeys {
+		if path == key || strings.HasPrefix(path, key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isProjectKeyParent checks if one of projectKeys is below the key at the dotted path.
+func isProjectKeyParent(path string) bool {
+	for _, key := range projectKeys {
+		if strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// projectLayer marks the layer of the project file as restricted, unless its directory is trusted or
+// it is the file given with --config.
+func projectLayer(layers []Layer) {
+	project := ProjectConfigPath()
+	if project == "" || project == configFile {
+		return
+	}
+
+	trusted := trustedProjects(layers, project)
+
+	for i := range layers {
+		if layers[i].Path == project && !isTrustedProject(project, trusted) {
+			layers[i].Restricted = true
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package config
+
+// Prompts is the configuration of the prompts.
+type Prompts struct {
+	// System replaces the default system prompt. It is a Go template, {{.Date}} is the current date and
+	// {{.Tools}} is the JSON description of the tools.
+	System string `yaml:"system,omitempty"`
+}
@@ -1,25 +1,31 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-
-	"github.com/kirsle/configdir"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	// configDir is the directory where the configuration files are stored.
-	configDir string
-)
+// Layer is a configuration file applied on top of the previous ones.
+type Layer struct {
+	// Path is the path of the file.
+	Path string
+	// Data is the content of the file.
+	Data []byte
+	// Restricted limits the layer to the keys a project file can set when its directory is not trusted,
+	// the environment variables are not interpolated either.
+	Restricted bool
+}
 
-// GetCurrentConfigurations gets the current configurations.
+// GetCurrentConfigurations gets the current configurations, merging the system, user, project and
+// --config files in that order.
 func GetCurrentConfigurations() (*Base, error) {
-	var base Base
-	if !DoesConfigExist() {
+	if configFile == "" && !DoesConfigExist() {
 		log.Warn("Configuration does not exist.")
 		log.Debug("Creating a new configuration.")
 
@@ -29,23 +35,86 @@ func GetCurrentConfigurations() (*Base, error) {
 			return nil, err
 		}
 	}
-	err := LoadConfig(&base)
-	if err != nil {
-		return nil, err
+
+	// The files are migrated in memory, they are only rewritten by config migrate or config set.
+	return ParseWith("", nil)
+}
+
+// ParseWith loads the configuration files like GetCurrentConfigurations, using data as the content
+// of the file at path instead of reading it. It is used to check a file before writing it.
+func ParseWith(path string, data []byte) (*Base, error) {
+	layers := make([]Layer, 0)
+	replaced := false
+
+	files := ConfigFiles()
+	if path != "" && !contains(files, path) {
+		files = append(files, path)
+	}
+
+	for _, file := range files {
+		if file == path {
+			layers = append(layers, Layer{Path: path, Data: data})
+			replaced = true
+			continue
+		}
+
+		log.Debug("Loading configuration from ", file)
+
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) && file == configFile {
+			return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, file)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, Layer{Path: file, Data: content})
+	}
+
+	if path != "" && !replaced {
+		layers = append(layers, Layer{Path: path, Data: data})
+	}
+
+	projectLayer(layers)
+
+	return ParseLayers(layers)
+}
+
+// Parse parses a single configuration file, see ParseLayers.
+func Parse(data []byte, path string) (*Base, error) {
+	return ParseLayers([]Layer{{Path: path, Data: data}})
+}
+
+// ParseLayers parses configuration files, each one applied on top of the previous ones. Environment
+// variables are interpolated and applied as overrides. Secret references are kept as they are, they
+// are resolved with ResolveSecret when they are used. Problems that don't prevent decoding are
+// reported by Validate.
+func ParseLayers(layers []Layer) (*Base, error) {
+	var base Base
+
+	for _, layer := range layers {
+		err := decode(layer.Data, layer.Path, layer.Restricted, &base)
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	applyProfile(&base, profileName(&base))
+	applyEnvOverrides(&base)
+
 	return &base, nil
 }
 
 // DoesConfigExist checks if the configuration exists.
 func DoesConfigExist() bool {
-	configPath := filepath.Join(configDir, "config.yaml")
-	_, err := os.Stat(configPath)
+	_, err := os.Stat(ConfigPath())
 	return !os.IsNotExist(err)
 }
 
-// CreateDefaultConfig creates the default configuration.
-func CreateDefaultConfig() error {
-	defaultConfiguration := Base{
+// DefaultConfig returns the default configuration.
+func DefaultConfig() *Base {
+	return &Base{
+		Version: CurrentVersion,
 		Assistants: Assistants{
 			Plugin: "ollama",
 			Ollama: Ollama{
@@ -57,33 +126,51 @@ func CreateDefaultConfig() error {
 				APIKey:   "<api_key>",
 				Endpoint: "https://api.openai.com/v1/",
 			},
+			Summarization: Summarization{
+				ChunkSize:   2048,
+				Concurrency: 2,
+			},
+		},
+		Tool: Tool{
+			HTTP: HTTP{
+				MaxRedirects:   5,
+				MaxBodySize:    5 << 20,
+				ConnectTimeout: 5 * time.Second,
+				ReadTimeout:    20 * time.Second,
+			},
+			Browser: BrowserTool{
+				SearchProvider: "duckduckgo",
+				MaxResults:     3,
+				Timeout:        20 * time.Second,
+			},
+		},
+		Log: Log{
+			Level:      "info",
+			Format:     "text",
+			MaxSize:    10,
+			MaxBackups: 3,
 		},
-		Tool: Tool{},
 	}
+}
 
-	// Create the configuration directory
-	err := os.MkdirAll(configDir, 0755)
-	if err != nil {
-		return err
-	}
+// CreateDefaultConfig creates the default configuration.
+func CreateDefaultConfig() error {
+	return WriteConfig(DefaultConfig())
+}
 
-	// Create the configuration file
-	configPath := filepath.Join(configDir, "config.yaml")
-	yamlFile, err := yaml.Marshal(defaultConfiguration)
-	if err != nil {
-		return err
-	}
-	err = os.WriteFile(configPath, yamlFile, 0644)
+// WriteConfig writes the configuration to the configuration file, replacing it.
+func WriteConfig(base *Base) error {
+	yamlFile, err := yaml.Marshal(base)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeFile(ConfigPath(), yamlFile)
 }
 
 // LoadConfig loads the configuration.
 func LoadConfig(config interface{}) error {
-	configPath := filepath.Join(configDir, "config.yaml")
+	configPath := ConfigPath()
 
 	log.Debug("Loading configuration from ", configPath)
 
@@ -94,15 +181,103 @@ func LoadConfig(config interface{}) error {
 		return err
 	}
 
-	// Unmarshal yamlFile
-	err = yaml.Unmarshal(yamlFile, config)
+	return decode(yamlFile, configPath, false, config)
+}
+
+// decode decodes a configuration file. When config is a *Base, the document is kept to report line
+// numbers, and the problems that don't prevent decoding are kept to be reported by Validate. A
+// restricted file is limited to the keys of an untrusted project file.
+func decode(data []byte, path string, restricted bool, config interface{}) error {
+	var document yaml.Node
+
+	err := yaml.Unmarshal(data, &document)
 	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	problems := make([]Problem, 0)
+
+	base, isBase := config.(*Base)
+	if isBase {
+		// Older files are migrated in memory, only the file changed by the config commands is rewritten.
+		// The migrations run first, the values they write can reference environment variables.
+		problems = append(problems, migrateLayer(&document, path)...)
+	}
+
+	if restricted {
+		restrictDocument(&document, path)
+	} else {
+		interpolateEnv(&document, path, &problems)
+	}
+
+	if isBase {
+		base.sources = append(base.sources, source{path: path, document: &document})
+		base.problems = append(base.problems, problems...)
+	} else if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	// An empty file has no content to decode.
+	if len(document.Content) == 0 {
+		return nil
+	}
+
+	err = document.Decode(config)
+	if problems, ok := typeErrorProblems(path, err); ok {
+		// The rest of the document is still decoded.
+		if isBase {
+			base.problems = append(base.problems, problems...)
+			return nil
+		}
+
+		return &ValidationError{Problems: problems}
+	}
+
+	return err
+}
+
+// writeFile writes the file atomically, creating its directory if needed and keeping its permissions.
+// New files are only readable by the user, they can hold secrets.
+func writeFile(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
 }
 
-func init() {
-	configDir = configdir.LocalConfig("pishia")
+// contains checks if the list contains the value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
 }
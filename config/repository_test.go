@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMissingConfigFile(t *testing.T) {
+	t.Setenv("PISHIA_HOME", t.TempDir())
+	t.Setenv("PISHIA_CONFIG", "")
+
+	SetConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	t.Cleanup(func() { SetConfigFile("") })
+
+	_, err := GetCurrentConfigurations()
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Fatalf("got error %v, want %v", err, ErrConfigNotFound)
+	}
+}
+
+func TestWriteFilePermissions(t *testing.T) {
+	dir := t.TempDir()
+
+	created := filepath.Join(dir, "config", "config.yaml")
+	if err := writeFile(created, []byte("version: 1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("got mode %o for a new file, want 600", mode)
+	}
+
+	existing := filepath.Join(dir, "existing.yaml")
+	if err := os.WriteFile(existing, []byte("version: 1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFile(existing, []byte("version: 1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = os.Stat(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0640 {
+		t.Errorf("got mode %o for an existing file, want its mode 640 to be kept", mode)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// Server is the configuration of the HTTP API started with pishia serve.
+type Server struct {
+	// Address is the address the server listens on, like 127.0.0.1:7070.
+	Address string `yaml:"address,omitempty"`
+	// Token is the bearer token the clients must send, a random one is generated at every start if it
+	// is empty.
+	Token string `yaml:"token,omitempty" secret:"true"`
+	// AllowedHosts are the host names the server answers to besides its address and localhost, like the
+	// name of a reverse proxy.
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty"`
+	// AllowedOrigins are the origins allowed to call the API from a browser, * allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// ShutdownTimeout is the time given to the running requests to end when the server stops.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout,omitempty"`
+	// MaxSessions is the maximum number of open sessions.
+	MaxSessions int `yaml:"max_sessions,omitempty"`
+	// SessionIdleTimeout is how long a session is kept without messages before it is deleted.
+	SessionIdleTimeout time.Duration `yaml:"session_idle_timeout,omitempty"`
+}
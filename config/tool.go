@@ -1,6 +1,72 @@
 package config
 
+import "time"
+
 // Tool is the configuration of the tool.
 type Tool struct {
-	// TODO
+	// HTTP is the configuration of the HTTP client used by the tools.
+	HTTP HTTP `yaml:"http,omitempty"`
+	// Set is the tool set to use. Only the enabled tools of the set are registered, all the enabled tools
+	// are registered if it is empty.
+	Set string `yaml:"set,omitempty"`
+	// Sets are named lists of tools, selected with set.
+	Sets map[string][]string `yaml:"sets,omitempty"`
+	// Browser is the configuration of the browser tool.
+	Browser BrowserTool `yaml:"browser,omitempty"`
+	// Reservation is the configuration of the reservation tool.
+	Reservation ReservationTool `yaml:"reservation,omitempty"`
+	// OpenAppMacOS is the configuration of the tool that opens applications on macOS.
+	OpenAppMacOS OpenAppMacOSTool `yaml:"open_app_macos,omitempty"`
+}
+
+// HTTP is the configuration of the HTTP client used by the tools.
+type HTTP struct {
+	// AllowedHosts are the hosts that can be reached even if they resolve to a private address.
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty"`
+	// AllowedNetworks are the CIDR ranges that can be reached even if they are private.
+	AllowedNetworks []string `yaml:"allowed_networks,omitempty"`
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int `yaml:"max_redirects,omitempty"`
+	// MaxBodySize is the maximum size in bytes of a response body.
+	MaxBodySize int64 `yaml:"max_body_size,omitempty"`
+	// ConnectTimeout is the timeout to connect to a host.
+	ConnectTimeout time.Duration `yaml:"connect_timeout,omitempty"`
+	// ReadTimeout is the timeout to read the whole response.
+	ReadTimeout time.Duration `yaml:"read_timeout,omitempty"`
+}
+
+// Toggle enables or disables a tool.
+type Toggle struct {
+	// Enabled enables the tool, the default of the tool is used if it is not set.
+	Enabled *bool `yaml:"enabled,omitempty"`
+}
+
+// IsEnabled checks if the tool is enabled, defaultValue is used if it is not set.
+func (t Toggle) IsEnabled(defaultValue bool) bool {
+	if t.Enabled == nil {
+		return defaultValue
+	}
+
+	return *t.Enabled
+}
+
+// BrowserTool is the configuration of the browser tool.
+type BrowserTool struct {
+	Toggle `yaml:",inline"`
+	// SearchProvider is the search engine used, only duckduckgo is supported.
+	SearchProvider string `yaml:"search_provider,omitempty"`
+	// MaxResults is the maximum number of search results read.
+	MaxResults int `yaml:"max_results,omitempty"`
+	// Timeout is the timeout to read a page.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// ReservationTool is the configuration of the reservation tool.
+type ReservationTool struct {
+	Toggle `yaml:",inline"`
+}
+
+// OpenAppMacOSTool is the configuration of the tool that opens applications on macOS.
+type OpenAppMacOSTool struct {
+	Toggle `yaml:",inline"`
 }
@@ -0,0 +1,634 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// typeErrorLine matches the line number of the errors reported by yaml.v3.
+	typeErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+	// placeholder matches the placeholders of the default configuration, like <api_key>.
+	placeholder = regexp.MustCompile(`^<[^<>]*>$`)
+	// yamlNodeType is the type of the raw YAML nodes, they are not checked for unknown keys.
+	yamlNodeType = reflect.TypeOf(yaml.Node{})
+)
+
+// Problem is a problem found in the configuration.
+type Problem struct {
+	// File is the file where the problem was found.
+	File string
+	// Line is the line of the file where the problem was found, 0 if unknown.
+	Line int
+	// Path is the dotted path of the key, like assistants.plugin.
+	Path string
+	// Message describes the problem.
+	Message string
+}
+
+// String formats the problem as file:line: path: message.
+func (p Problem) String() string {
+	location := p.File
+
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, p.Line)
+	}
+
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", location, p.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", location, p.Path, p.Message)
+}
+
+// ValidationError is returned when the configuration is not valid, it contains all the problems found.
+type ValidationError struct {
+	// Problems are the problems found in the configuration.
+	Problems []Problem
+}
+
+// Error returns all the problems, one per line.
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, "invalid configuration:")
+
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Names are the names of the available assistant plugins and tools, the configuration can only
+// reference them.
+type Names struct {
+	// Assistants are the names of the assistant plugins.
+	Assistants []string
+	// Tools are the names of the tools.
+	Tools []string
+}
+
+// Validate validates the configuration against the available plugins and tools. All the problems are
+// reported at once, in a *ValidationError.
+func Validate(base *Base, names Names) error {
+	v := &validator{base: base}
+	v.problems = append(v.problems, base.problems...)
+
+	for _, source := range base.sources {
+		v.file = source.path
+		v.checkKnownFields(source.document, reflect.TypeOf(*base), "")
+	}
+
+	if base.Version > CurrentVersion {
+		v.add("version", "version %d is newer than the supported version %d, please upgrade Pishia", base.Version, CurrentVersion)
+	}
+
+	v.checkProfiles()
+	v.checkAssistants(names.Assistants)
+	v.checkTool(names.Tools)
+	v.checkLog()
+	v.checkServer()
+
+	return v.err()
+}
+
+// ValidateOllama validates only the configuration of the Ollama assistant, for the commands that only
+// talk to the Ollama: a problem in the rest of the configuration doesn't prevent them from running.
+func ValidateOllama(base *Base) error {
+	v := &validator{base: base}
+
+	for _, problem := range base.problems {
+		if problem.Path == "assistants.ollama" || strings.HasPrefix(problem.Path, "assistants.ollama.") {
+			v.problems = append(v.problems, problem)
+		}
+	}
+
+	v.checkRequired("assistants.ollama.endpoint", base.Assistants.Ollama.Endpoint)
+	v.checkOllama()
+
+	return v.err()
+}
+
+// ValidateOpenAI validates only the configuration of the OpenAI assistant, for the commands that only
+// talk to the OpenAI API.
+func ValidateOpenAI(base *Base) error {
+	v := &validator{base: base}
+
+	for _, problem := range base.problems {
+		if problem.Path == "assistants.openai" || strings.HasPrefix(problem.Path, "assistants.openai.") {
+			v.problems = append(v.problems, problem)
+		}
+	}
+
+	v.checkURL("assistants.openai.endpoint", base.Assistants.OpenAI.Endpoint)
+	v.checkSecret("assistants.openai.api_key", base.Assistants.OpenAI.APIKey)
+
+	return v.err()
+}
+
+// validator collects the problems of a configuration.
+type validator struct {
+	base     *Base
+	problems []Problem
+	// file is the file of the YAML nodes being checked.
+	file string
+}
+
+// err returns the problems sorted by file and line in a *ValidationError, nil if there is none.
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].File != v.problems[j].File {
+			return v.problems[i].File < v.problems[j].File
+		}
+		return v.problems[i].Line < v.problems[j].Line
+	})
+
+	return &ValidationError{Problems: v.problems}
+}
+
+// add adds a problem for the key at path.
+func (v *validator) add(path string, format string, args ...interface{}) {
+	file, line := v.base.locate(path)
+
+	v.problems = append(v.problems, Problem{
+		File:    file,
+		Line:    line,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addAt adds a problem found at a YAML node of the file being checked.
+func (v *validator) addAt(node *yaml.Node, path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		File:    v.file,
+		Line:    node.Line,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkKnownFields walks the YAML node and the Go type side by side, reporting the keys that don't
+// match any field.
+func (v *validator) checkKnownFields(node *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == yamlNodeType {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, content := range node.Content {
+			v.checkKnownFields(content, t, path)
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := yamlFields(t)
+
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				keyPath := joinPath(path, key.Value)
+
+				field, ok := fields[key.Value]
+				if !ok {
+					v.addAt(key, keyPath, "unknown key %q", key.Value)
+					continue
+				}
+
+				v.checkKnownFields(value, field.Type, keyPath)
+			}
+		case reflect.Map:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				v.checkKnownFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range node.Content {
+				v.checkKnownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+}
+
+// checkProfiles checks the keys of the profiles and that the profiles they inherit from exist.
+func (v *validator) checkProfiles() {
+	for _, name := range v.base.ProfileNames() {
+		profile := v.base.Profiles[name]
+		path := "profiles." + name
+
+		v.file, _ = v.base.locate(path)
+		v.checkKnownFields(&profile.Assistants, reflect.TypeOf(Assistants{}), path+".assistants")
+		v.checkKnownFields(&profile.Tool, reflect.TypeOf(Tool{}), path+".tool")
+		v.checkKnownFields(&profile.Prompts, reflect.TypeOf(Prompts{}), path+".prompts")
+
+		if _, err := profileChain(v.base, name); err != nil {
+			v.add(path+".inherits", "%v", err)
+		}
+	}
+}
+
+// checkAssistants checks the configuration of the assistants.
+func (v *validator) checkAssistants(plugins []string) {
+	assistants := v.base.Assistants
+
+	known := false
+	for _, plugin := range plugins {
+		if plugin == assistants.Plugin {
+			known = true
+			break
+		}
+	}
+
+	if assistants.Plugin == "" {
+		v.add("assistants.plugin", "is required, available plugins: %s", strings.Join(plugins, ", "))
+	} else if !known {
+		v.add("assistants.plugin", "unknown plugin %q, available plugins: %s", assistants.Plugin, strings.Join(plugins, ", "))
+	}
+
+	v.checkOllama()
+	v.checkURL("assistants.openai.endpoint", assistants.OpenAI.Endpoint)
+
+	switch assistants.Plugin {
+	case "ollama":
+		v.checkRequired("assistants.ollama.model", assistants.Ollama.Model)
+		v.checkRequired("assistants.ollama.endpoint", assistants.Ollama.Endpoint)
+	case "openai":
+		v.checkRequired("assistants.openai.model", assistants.OpenAI.Model)
+		v.checkSecret("assistants.openai.api_key", assistants.OpenAI.APIKey)
+	}
+
+	if assistants.Summarization.ChunkSize < 0 {
+		v.add("assistants.summarization.chunk_size", "must be positive, got %d", assistants.Summarization.ChunkSize)
+	}
+
+	if assistants.Summarization.Concurrency < 0 {
+		v.add("assistants.summarization.concurrency", "must be positive, got %d", assistants.Summarization.Concurrency)
+	}
+
+	v.checkGeneration("assistants.generation", assistants.Generation)
+}
+
+// checkOllama checks the endpoint and the timeouts of the Ollama assistant.
+func (v *validator) checkOllama() {
+	ollama := v.base.Assistants.Ollama
+
+	v.checkURL("assistants.ollama.endpoint", ollama.Endpoint)
+
+	if ollama.DialTimeout < 0 {
+		v.add("assistants.ollama.dial_timeout", "must be positive, got %s", ollama.DialTimeout)
+	}
+
+	if ollama.ResponseHeaderTimeout < 0 {
+		v.add("assistants.ollama.response_header_timeout", "must be positive, got %s", ollama.ResponseHeaderTimeout)
+	}
+}
+
+// checkGeneration checks the parameters of the generation of the answers.
+func (v *validator) checkGeneration(path string, generation Generation) {
+	if t := generation.Temperature; t != nil && (*t < 0 || *t > 2) {
+		v.add(path+".temperature", "must be between 0 and 2, got %g", *t)
+	}
+
+	if p := generation.TopP; p != nil && (*p < 0 || *p > 1) {
+		v.add(path+".top_p", "must be between 0 and 1, got %g", *p)
+	}
+
+	if generation.MaxTokens < 0 {
+		v.add(path+".max_tokens", "must be positive, got %d", generation.MaxTokens)
+	}
+
+	if generation.NumCtx < 0 {
+		v.add(path+".num_ctx", "must be positive, got %d", generation.NumCtx)
+	}
+
+	if p := generation.RepeatPenalty; p != nil && *p < 0 {
+		v.add(path+".repeat_penalty", "must be positive, got %g", *p)
+	}
+
+	for i, stop := range generation.Stop {
+		if stop == "" {
+			v.add(fmt.Sprintf("%s.stop[%d]", path, i), "must not be empty")
+		}
+	}
+
+	switch generation.Format {
+	case "", "text", "json":
+	default:
+		v.add(path+".format", "unknown format %q, available formats: text, json", generation.Format)
+	}
+}
+
+// checkTool checks the configuration of the tools.
+func (v *validator) checkTool(tools []string) {
+	tool := v.base.Tool
+	http := tool.HTTP
+
+	known := make(map[string]bool)
+	for _, name := range tools {
+		known[name] = true
+	}
+
+	if _, ok := tool.Sets[tool.Set]; tool.Set != "" && !ok {
+		v.add("tool.set", "unknown tool set %q", tool.Set)
+	}
+
+	for set, names := range tool.Sets {
+		for i, name := range names {
+			if !known[name] {
+				v.add(fmt.Sprintf("tool.sets.%s[%d]", set, i), "unknown tool %q, available tools: %s", name, strings.Join(tools, ", "))
+			}
+		}
+	}
+
+	if tool.Browser.SearchProvider != "" && tool.Browser.SearchProvider != "duckduckgo" {
+		v.add("tool.browser.search_provider", "unknown search provider %q, available providers: duckduckgo", tool.Browser.SearchProvider)
+	}
+
+	if tool.Browser.MaxResults < 0 {
+		v.add("tool.browser.max_results", "must be positive, got %d", tool.Browser.MaxResults)
+	}
+
+	if tool.Browser.Timeout < 0 {
+		v.add("tool.browser.timeout", "must be positive, got %s", tool.Browser.Timeout)
+	}
+
+	for i, cidr := range http.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			v.add(fmt.Sprintf("tool.http.allowed_networks[%d]", i), "invalid CIDR %q", cidr)
+		}
+	}
+
+	if http.MaxRedirects < 0 {
+		v.add("tool.http.max_redirects", "must be positive, got %d", http.MaxRedirects)
+	}
+
+	if http.MaxBodySize < 0 {
+		v.add("tool.http.max_body_size", "must be positive, got %d", http.MaxBodySize)
+	}
+
+	if http.ConnectTimeout < 0 {
+		v.add("tool.http.connect_timeout", "must be positive, got %s", http.ConnectTimeout)
+	}
+
+	if http.ReadTimeout < 0 {
+		v.add("tool.http.read_timeout", "must be positive, got %s", http.ReadTimeout)
+	}
+}
+
+// checkLog checks the configuration of the logs.
+func (v *validator) checkLog() {
+	logConfig := v.base.Log
+
+	if _, err := log.ParseLevel(logConfig.Level); logConfig.Level != "" && err != nil {
+		v.add("log.level", "unknown level %q, available levels: trace, debug, info, warn, error, fatal, panic", logConfig.Level)
+	}
+
+	if logConfig.Format != "" && logConfig.Format != "text" && logConfig.Format != "json" {
+		v.add("log.format", "unknown format %q, available formats: text, json", logConfig.Format)
+	}
+
+	if logConfig.MaxSize < 0 {
+		v.add("log.max_size", "must be positive, got %d", logConfig.MaxSize)
+	}
+
+	if logConfig.MaxBackups < 0 {
+		v.add("log.max_backups", "must be positive, got %d", logConfig.MaxBackups)
+	}
+}
+
+// checkServer checks the configuration of the HTTP API.
+func (v *validator) checkServer() {
+	server := v.base.Server
+
+	if server.Address != "" {
+		if _, _, err := net.SplitHostPort(server.Address); err != nil {
+			v.add("server.address", "invalid address %q: %v", server.Address, err)
+		}
+	}
+
+	if server.Token != "" {
+		v.checkSecret("server.token", server.Token)
+	}
+
+	for i, origin := range server.AllowedOrigins {
+		if origin != "*" {
+			v.checkURL(fmt.Sprintf("server.allowed_origins[%d]", i), origin)
+		}
+	}
+
+	if server.ShutdownTimeout < 0 {
+		v.add("server.shutdown_timeout", "must be positive, got %s", server.ShutdownTimeout)
+	}
+
+	if server.MaxSessions < 0 {
+		v.add("server.max_sessions", "must be positive, got %d", server.MaxSessions)
+	}
+
+	if server.SessionIdleTimeout < 0 {
+		v.add("server.session_idle_timeout", "must be positive, got %s", server.SessionIdleTimeout)
+	}
+}
+
+// checkRequired checks that the value is set.
+func (v *validator) checkRequired(path string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "is required")
+	}
+}
+
+// checkSecret checks that the secret is set and is not a placeholder.
+func (v *validator) checkSecret(path string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "secret is required")
+		return
+	}
+
+	if placeholder.MatchString(strings.TrimSpace(value)) {
+		v.add(path, "secret is not set, found the placeholder %q", value)
+	}
+
+	if envReference.MatchString(value) {
+		v.add(path, "secret references an environment variable that was not interpolated: %q", value)
+	}
+
+	// The commands are only run when the secret is used, the files can be checked without reading them.
+	if file, ok := strings.CutPrefix(value, fileSecretPrefix); ok {
+		if _, err := os.Stat(expandHome(strings.TrimSpace(file))); err != nil {
+			v.add(path, "secret file: %v", err)
+		}
+	}
+}
+
+// checkURL checks that the value, if set, is an absolute http or https URL.
+func (v *validator) checkURL(path string, value string) {
+	if value == "" {
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		v.add(path, "invalid URL %q: %v", value, err)
+		return
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		v.add(path, "invalid URL %q: scheme must be http or https", value)
+		return
+	}
+
+	if u.Host == "" {
+		v.add(path, "invalid URL %q: host is missing", value)
+	}
+}
+
+// typeErrorProblems converts the errors of yaml.v3 into problems.
+func typeErrorProblems(file string, err error) ([]Problem, bool) {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return nil, false
+	}
+
+	problems := make([]Problem, 0, len(typeErr.Errors))
+
+	for _, message := range typeErr.Errors {
+		problem := Problem{File: file, Message: message}
+
+		if matches := typeErrorLine.FindStringSubmatch(message); matches != nil {
+			problem.Line, _ = strconv.Atoi(matches[1])
+			problem.Message = matches[2]
+		}
+
+		problems = append(problems, problem)
+	}
+
+	return problems, true
+}
+
+// yamlFields returns the fields of a struct indexed by their YAML key, inlined structs included.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if strings.Contains(options, "inline") && field.Type.Kind() == reflect.Struct {
+			for key, inlined := range yamlFields(field.Type) {
+				fields[key] = inlined
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field
+	}
+
+	return fields
+}
+
+// lineOf returns the line of the key at the dotted path, or of its closest parent if it isn't set, and
+// the number of keys of the path found in the document.
+func lineOf(document *yaml.Node, path string) (int, int) {
+	if document == nil {
+		return 0, -1
+	}
+
+	node := document
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := 0
+
+	for depth, key := range splitPath(path) {
+		index := -1
+		if open := strings.Index(key, "["); open >= 0 && strings.HasSuffix(key, "]") {
+			index, _ = strconv.Atoi(key[open+1 : len(key)-1])
+			key = key[:open]
+		}
+
+		value := mappingValue(node, key)
+		if value == nil {
+			return line, depth
+		}
+
+		node = value
+		line = node.Line
+
+		if index >= 0 {
+			if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+				return line, depth
+			}
+			node = node.Content[index]
+			line = node.Line
+		}
+	}
+
+	return line, len(splitPath(path))
+}
+
+// mappingValue returns the value of the key in a mapping node, or nil if the key isn't there.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// splitPath splits a dotted path into its keys.
+func splitPath(path string) []string {
+	if path == "" {
+		return []string{}
+	}
+
+	return strings.Split(path, ".")
+}
+
+// joinPath joins a dotted path and a key.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
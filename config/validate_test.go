@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateOllama(t *testing.T) {
+	base, err := Parse([]byte(`assistants:
+    plugin: openai
+    ollama:
+        endpoint: http://localhost:11434
+    openai:
+        endpoint: "not a url"
+log:
+    level: loud
+`), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ValidateOllama(base); err != nil {
+		t.Errorf("ValidateOllama reported the problems of the other sections: %v", err)
+	}
+
+	base.Assistants.Ollama.Endpoint = "localhost:11434"
+
+	var validationErr *ValidationError
+	if err := ValidateOllama(base); !errors.As(err, &validationErr) || validationErr.Problems[0].Path != "assistants.ollama.endpoint" {
+		t.Errorf("got error %v, want a problem with assistants.ollama.endpoint", err)
+	}
+}
+
+func TestValidateOpenAI(t *testing.T) {
+	base, err := Parse([]byte(`assistants:
+    plugin: openai
+    ollama:
+        endpoint: "not a url"
+    openai:
+        api_key: sk-test
+log:
+    level: loud
+`), "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ValidateOpenAI(base); err != nil {
+		t.Errorf("ValidateOpenAI reported the problems of the other sections: %v", err)
+	}
+
+	for _, apiKey := range []string{"", "<api_key>"} {
+		base.Assistants.OpenAI.APIKey = apiKey
+
+		var validationErr *ValidationError
+		if err := ValidateOpenAI(base); !errors.As(err, &validationErr) || validationErr.Problems[0].Path != "assistants.openai.api_key" {
+			t.Errorf("API key %q: got error %v, want a problem with assistants.openai.api_key", apiKey, err)
+		}
+	}
+}
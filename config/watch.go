@@ -0,0 +1,76 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Watch polls the configuration files every interval until the context is done. When its content
+// change, the configuration is loaded and validated against the available names, and apply is
+// called with it. Invalid configurations are reported and skipped, so the current one keeps running.
+func Watch(ctx context.Context, interval time.Duration, names Names, apply func(*Base) error) {
+	last := filesHash(ConfigFiles())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		files := ConfigFiles()
+
+		current := filesHash(files)
+		if current == nil || bytes.Equal(current, last) {
+			continue
+		}
+		last = current
+
+		log.Infof("Configuration changed, reloading %s", strings.Join(files, ", "))
+
+		base, err := GetCurrentConfigurations()
+		if err == nil {
+			err = Validate(base, names)
+		}
+
+		if err != nil {
+			log.Errorf("Configuration not reloaded, keeping the current one: %v", err)
+			continue
+		}
+
+		err = apply(base)
+		if err != nil {
+			log.Errorf("Configuration not applied, keeping the current one: %v", err)
+			continue
+		}
+
+		log.Info("Configuration reloaded")
+	}
+}
+
+// filesHash returns the hash of the paths and contents of the files, or nil if one of them can't be
+// read. Editors often replace the file while saving it, so a missing file is not a change.
+func filesHash(paths []string) []byte {
+	hash := sha256.New()
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		hash.Write([]byte(path))
+		hash.Write(content)
+	}
+
+	return hash.Sum(nil)
+}
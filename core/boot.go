@@ -1,19 +1,104 @@
 package core
 
 import (
+	"context"
+	"sync"
+
 	"github.com/Pishia-IA/core/config"
 	"github.com/Pishia-IA/core/plugins/assistants"
 	"github.com/Pishia-IA/core/plugins/tools"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// current is the configuration the core runs with.
+	current *config.Base
+	// mu protects current, it is replaced when the configuration is reloaded.
+	mu sync.RWMutex
 )
 
 // Boot starts the core.
 func Boot() error {
-	config, err := config.GetCurrentConfigurations()
+	return BootWith(nil)
+}
+
+// BootWith starts the core, calling override to change the configuration before it is validated, like
+// the flags of a command do.
+func BootWith(override func(cfg *config.Base)) error {
+	cfg, err := config.GetCurrentConfigurations()
+	if err != nil {
+		return err
+	}
+
+	if override != nil {
+		override(cfg)
+	}
+
+	err = config.Validate(cfg, Names())
+	if err != nil {
+		return err
+	}
+
+	err = ConfigureLogging(cfg.Log)
 	if err != nil {
 		return err
 	}
 
-	tools.StartTools(config)
-	assistants.StartAssistants(config)
+	tools.StartTools(cfg)
+	assistants.StartAssistants(cfg)
+	setConfig(cfg)
+	return nil
+}
+
+// Config returns the configuration the core runs with.
+func Config() *config.Base {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// setConfig replaces the configuration the core runs with.
+func setConfig(cfg *config.Base) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = cfg
+}
+
+// Names returns the names of the assistant plugins and tools a configuration can reference, the tools
+// not available on this system included.
+func Names() config.Names {
+	return config.Names{
+		Assistants: assistants.Plugins(),
+		Tools:      tools.RegisteredNames(),
+	}
+}
+
+// Reload applies a new configuration: the tools are registered again and the default assistant is
+// rebuilt, continuing the current conversation. If the assistant can't be set up, nothing changes, the
+// logs included.
+func Reload(ctx context.Context, cfg *config.Base) error {
+	// The logs are checked first and only configured once the assistant is set up.
+	_, _, _, err := logSettings(cfg.Log)
+	if err != nil {
+		return err
+	}
+
+	previousTools := tools.GetRepository()
+	tools.StartTools(cfg)
+
+	err = assistants.Reload(ctx, cfg)
+	if err != nil {
+		tools.SetRepository(previousTools)
+		return err
+	}
+
+	setConfig(cfg)
+
+	err = ConfigureLogging(cfg.Log)
+	if err != nil {
+		log.Warnf("Configuring the logs: %v", err)
+	}
+
 	return nil
 }
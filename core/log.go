@@ -0,0 +1,170 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Pishia-IA/core/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// logFlags are the options of the logs given with the flags, they override the configuration.
+	logFlags config.Log
+	// logFile is the rotating log file, nil when the logs are written to the standard error.
+	logFile *lumberjack.Logger
+
+	// session is the hook adding the session ID to the logs, added once by SetSessionID.
+	session = &sessionHook{}
+	// sessionHookOnce adds the hook of the session once.
+	sessionHookOnce sync.Once
+)
+
+// sessionHook adds the session ID to every log.
+type sessionHook struct {
+	// mu protects id, it changes when a new session starts.
+	mu sync.RWMutex
+	// id is the session ID.
+	id string
+}
+
+// Levels returns the levels of the logs the hook applies to.
+func (h *sessionHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire adds the session ID to the log.
+func (h *sessionHook) Fire(entry *log.Entry) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := entry.Data["session"]; !ok && h.id != "" {
+		entry.Data["session"] = h.id
+	}
+
+	return nil
+}
+
+// SetLogFlags sets the options of the logs given with the flags, they override the configuration.
+func SetLogFlags(flags config.Log) {
+	logFlags = flags
+}
+
+// ConfigureLogging sets the level, format and destination of the logs from the configuration, overridden
+// by the flags. The logs are written to the standard error unless a log file is set.
+func ConfigureLogging(cfg config.Log) error {
+	cfg, level, formatter, err := logSettings(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.SetFormatter(formatter)
+	log.SetLevel(level)
+
+	if cfg.File == "" {
+		log.SetOutput(os.Stderr)
+		closeLogFile()
+		return nil
+	}
+
+	path := cfg.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.StateDir(), path)
+	}
+
+	if logFile != nil && logFile.Filename == path {
+		logFile.MaxSize = cfg.MaxSize
+		logFile.MaxBackups = cfg.MaxBackups
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.MaxSize,
+		MaxBackups: cfg.MaxBackups,
+	}
+	log.SetOutput(file)
+
+	closeLogFile()
+	logFile = file
+
+	return nil
+}
+
+// logSettings returns the configuration of the logs overridden by the flags, with its level and its
+// formatter, without applying them.
+func logSettings(cfg config.Log) (config.Log, log.Level, log.Formatter, error) {
+	if logFlags.Level != "" {
+		cfg.Level = logFlags.Level
+	}
+
+	if logFlags.Format != "" {
+		cfg.Format = logFlags.Format
+	}
+
+	if logFlags.File != "" {
+		cfg.File = logFlags.File
+	}
+
+	level := log.InfoLevel
+	if cfg.Level != "" {
+		var err error
+
+		level, err = log.ParseLevel(cfg.Level)
+		if err != nil {
+			return cfg, level, nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+		}
+	}
+
+	switch cfg.Format {
+	case "", "text":
+		return cfg, level, &log.TextFormatter{}, nil
+	case "json":
+		return cfg, level, &log.JSONFormatter{}, nil
+	}
+
+	return cfg, level, nil, fmt.Errorf("invalid log format %q, expected text or json", cfg.Format)
+}
+
+// SetSessionID adds the session ID to every log, to find the logs of a session. It replaces the ID of
+// the previous session.
+func SetSessionID(id string) {
+	session.mu.Lock()
+	session.id = id
+	session.mu.Unlock()
+
+	sessionHookOnce.Do(func() {
+		log.AddHook(session)
+	})
+}
+
+// NewSessionID returns a random session ID.
+func NewSessionID() string {
+	id := make([]byte, 8)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// closeLogFile closes the log file, if any.
+func closeLogFile() {
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
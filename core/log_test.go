@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSetSessionID(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	SetSessionID("first")
+	SetSessionID("second")
+
+	hooks := 0
+	for _, hook := range log.StandardLogger().Hooks[log.InfoLevel] {
+		if hook == session {
+			hooks++
+		}
+	}
+
+	if hooks != 1 {
+		t.Errorf("got %d session hooks, want 1", hooks)
+	}
+
+	log.Info("hello")
+	if !strings.Contains(out.String(), "session=second") || strings.Contains(out.String(), "first") {
+		t.Errorf("got log %q, want only the second session", out.String())
+	}
+}
+
+func TestReloadFailureKeepsLogs(t *testing.T) {
+	// The Ollama rejects every request, the assistant can't be set up.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "bad request"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	log.SetLevel(log.InfoLevel)
+
+	cfg := config.DefaultConfig()
+	cfg.Assistants.Ollama.Endpoint = server.URL
+	cfg.Assistants.Ollama.Model = "llama3"
+	cfg.Log.Level = "debug"
+
+	err := Reload(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("the reload must fail")
+	}
+
+	if level := log.GetLevel(); level != log.InfoLevel {
+		t.Errorf("got log level %s after a failed reload, want %s", level, log.InfoLevel)
+	}
+}
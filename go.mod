@@ -3,18 +3,22 @@ module github.com/Pishia-IA/core
 go 1.22.2
 
 require (
+	github.com/PuerkitoBio/goquery v1.8.1
+	github.com/alecthomas/chroma/v2 v2.14.0
 	github.com/gelembjuk/articletext v0.0.0-20231013143648-bc7a97ba132a
 	github.com/kirsle/configdir v0.0.0-20170128060238-e45d2f54772f
-	github.com/sap-nocops/duckduckgogo v0.0.0-20201102135645-176990152850
-	github.com/sashabaranov/go-openai v1.23.0
+	github.com/peterh/liner v1.2.2
+	github.com/sashabaranov/go-openai v1.26.3
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/term v0.20.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/PuerkitoBio/goquery v1.8.1 // indirect
 	github.com/andybalholm/cascadia v1.3.1 // indirect
+	github.com/dlclark/regexp2 v1.11.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/jaytaylor/html2text v0.0.0-20230321000545-74c2419ad056 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
@@ -22,6 +26,6 @@ require (
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/ssor/bom v0.0.0-20170718123548-6386211fdfcf // indirect
 	golang.org/x/net v0.7.0 // indirect
-	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
 	gopkg.in/neurosnap/sentences.v1 v1.0.7 // indirect
 )
@@ -0,0 +1,197 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/plugins/tools"
+	"github.com/Pishia-IA/core/thirdparty/ollama/ollamatest"
+)
+
+// recorder keeps the events of an assistant.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// handle records an event.
+func (r *recorder) handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// count returns the number of events of the type.
+func (r *recorder) count(eventType string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, event := range r.events {
+		if event.Type == eventType {
+			n++
+		}
+	}
+
+	return n
+}
+
+// testConfig starts a fake Ollama with the models and returns a configuration using it, with no tools.
+func testConfig(t *testing.T, models ...string) (*ollamatest.Server, *config.Base) {
+	t.Helper()
+
+	server := ollamatest.NewServer(models...)
+	t.Cleanup(server.Close)
+
+	previous := tools.GetRepository()
+	tools.SetRepository(tools.NewToolRepository())
+	t.Cleanup(func() { tools.SetRepository(previous) })
+
+	cfg := config.DefaultConfig()
+	cfg.Assistants.Ollama.Endpoint = server.URL
+	cfg.Assistants.Ollama.Model = "llama3"
+	cfg.Assistants.OpenAI.Endpoint = server.URL + "/v1"
+	cfg.Assistants.OpenAI.APIKey = "test"
+	cfg.Assistants.OpenAI.Model = "llama3"
+
+	return server, cfg
+}
+
+// ask sends the prompt to the assistant and returns the answer, without the surrounding spaces.
+func ask(t *testing.T, assistant Assistant, prompt string) string {
+	t.Helper()
+
+	var answer strings.Builder
+	var answerErr error
+
+	err := assistant.SendRequest(context.Background(), prompt, func(output string, err error) {
+		if err != nil {
+			answerErr = err
+			return
+		}
+
+		answer.WriteString(output)
+	})
+	if err == nil {
+		err = answerErr
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(answer.String())
+}
+
+func TestOllama(t *testing.T) {
+	server, cfg := testConfig(t)
+
+	events := &recorder{}
+	assistant := NewOllama(cfg)
+	assistant.SetEventHandler(events.handle)
+
+	err := assistant.Setup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.HasModel("llama3") || events.count(EventPullProgress) == 0 {
+		t.Error("the missing model must be pulled with its progress")
+	}
+
+	if answer := ask(t, assistant, "hello"); answer != "[llama3] echo: hello" {
+		t.Errorf("got answer %q, want %q", answer, "[llama3] echo: hello")
+	}
+
+	if events.count(EventUsage) != 1 {
+		t.Errorf("got %d usage events, want 1", events.count(EventUsage))
+	}
+
+	history := assistant.History()
+	if len(history) != 3 || history[0].Role != "system" || history[2].Role != "assistant" {
+		t.Errorf("got history %v, want the system prompt, the question and the answer", history)
+	}
+}
+
+func TestOllamaNoPull(t *testing.T) {
+	server, cfg := testConfig(t)
+
+	assistant := NewOllama(cfg)
+	assistant.NoPull = true
+
+	err := assistant.Setup(context.Background())
+	if err == nil {
+		t.Fatal("setting up a missing model must fail")
+	}
+
+	if server.HasModel("llama3") {
+		t.Error("the missing model must not be pulled")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	_, cfg := testConfig(t, "llama3")
+
+	events := &recorder{}
+	assistant := NewOpenAI(cfg)
+	assistant.SetEventHandler(events.handle)
+
+	err := assistant.Setup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if answer := ask(t, assistant, "hello"); answer != "[llama3] echo: hello" {
+		t.Errorf("got answer %q, want %q", answer, "[llama3] echo: hello")
+	}
+
+	if events.count(EventUsage) != 1 {
+		t.Errorf("got %d usage events, want 1", events.count(EventUsage))
+	}
+}
+
+func TestGenerationOnlyForAnswers(t *testing.T) {
+	server, cfg := testConfig(t, "llama3")
+
+	temperature := 0.2
+	cfg.Assistants.Generation.Temperature = &temperature
+	cfg.Assistants.Generation.Format = "json"
+
+	assistant := NewOllama(cfg)
+
+	_, err := assistant.SendRequestWithnoMemory(context.Background(), []string{"summarize"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ask(t, assistant, "hello")
+
+	var bodies []map[string]interface{}
+	for _, request := range server.Requests() {
+		if request.Path != "/api/chat" {
+			continue
+		}
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(request.Body, &body); err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, body)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d chats, want 2", len(bodies))
+	}
+
+	if _, ok := bodies[0]["options"]; ok || bodies[0]["format"] != nil {
+		t.Errorf("the request without memory must use the defaults of the model, got %v", bodies[0])
+	}
+
+	if _, ok := bodies[1]["options"]; !ok || bodies[1]["format"] != "json" {
+		t.Errorf("the answer to the user must use the parameters of the generation, got %v", bodies[1])
+	}
+}
@@ -0,0 +1,101 @@
+package assistants
+
+import "time"
+
+const (
+	// EventToken is a part of the answer, as it is streamed.
+	EventToken = "token"
+	// EventToolCallStarted is sent when the assistant starts running a tool.
+	EventToolCallStarted = "tool_call_started"
+	// EventToolCallFinished is sent when a tool has run, with its duration and error.
+	EventToolCallFinished = "tool_call_finished"
+	// EventError is sent when the request fails.
+	EventError = "error"
+	// EventMessage is the whole answer, sent when the request is done.
+	EventMessage = "message"
+	// EventUsage is the number of tokens used by the request, when the backend reports it.
+	EventUsage = "usage"
+	// EventPullProgress is the progress of the download of a model, sent while the assistant is set up.
+	EventPullProgress = "pull_progress"
+)
+
+// Event is something that happened while the assistant answered a request.
+type Event struct {
+	// Type is the type of the event, one of the Event constants.
+	Type string `json:"type"`
+	// Content is the text of the token and message events.
+	Content string `json:"content,omitempty"`
+	// Tool is the name of the tool of the tool call events.
+	Tool string `json:"tool,omitempty"`
+	// Arguments are the arguments of the tool call events.
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	// DurationMs is the time the tool took to run, in milliseconds.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// Error is the error of the error and tool_call_finished events.
+	Error string `json:"error,omitempty"`
+	// Usage is the number of tokens of the usage event.
+	Usage *Usage `json:"usage,omitempty"`
+	// Pull is the progress of the pull_progress event.
+	Pull *PullProgress `json:"pull,omitempty"`
+}
+
+// PullProgress is the progress of the download of a model.
+type PullProgress struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Status is the step of the download, like pulling a layer or verifying its digest.
+	Status string `json:"status"`
+	// Digest is the digest of the layer being downloaded.
+	Digest string `json:"digest,omitempty"`
+	// Total is the size of the layer in bytes.
+	Total int64 `json:"total,omitempty"`
+	// Completed is the number of bytes of the layer downloaded.
+	Completed int64 `json:"completed,omitempty"`
+}
+
+// Usage is the number of tokens used by a request.
+type Usage struct {
+	// PromptTokens is the number of tokens of the prompt.
+	PromptTokens int `json:"prompt_tokens"`
+	// CompletionTokens is the number of tokens of the answer.
+	CompletionTokens int `json:"completion_tokens"`
+	// TotalTokens is the number of tokens of the prompt and the answer.
+	TotalTokens int `json:"total_tokens"`
+}
+
+// EventHandler receives the events of an assistant.
+type EventHandler func(event Event)
+
+// events sends the events of an assistant to its handler, it is embedded in the assistants.
+type events struct {
+	// handler receives the events, they are dropped when it is nil.
+	handler EventHandler
+}
+
+// SetEventHandler sets the handler that receives the events of the assistant.
+func (e *events) SetEventHandler(handler EventHandler) {
+	e.handler = handler
+}
+
+// emit sends an event to the handler.
+func (e *events) emit(event Event) {
+	if e.handler != nil {
+		e.handler(event)
+	}
+}
+
+// emitToolCall sends the tool_call_finished event of a tool that started at start.
+func (e *events) emitToolCall(name string, arguments map[string]interface{}, start time.Time, err error) {
+	event := Event{
+		Type:       EventToolCallFinished,
+		Tool:       name,
+		Arguments:  arguments,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	e.emit(event)
+}
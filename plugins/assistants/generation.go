@@ -0,0 +1,77 @@
+package assistants
+
+import (
+	"math"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// formatJSON is the format of the answers that are a JSON object.
+const formatJSON = "json"
+
+// ollamaOptions maps the parameters of the generation onto the options of the Ollama.
+func ollamaOptions(generation config.Generation) *ollama.Options {
+	return &ollama.Options{
+		Temperature:   generation.Temperature,
+		TopP:          generation.TopP,
+		NumCtx:        generation.NumCtx,
+		NumPredict:    generation.MaxTokens,
+		Seed:          generation.Seed,
+		Stop:          generation.Stop,
+		RepeatPenalty: generation.RepeatPenalty,
+	}
+}
+
+// ollamaFormat maps the format of the answers onto the format of the Ollama, empty for text.
+func ollamaFormat(format string) string {
+	if format == formatJSON {
+		return formatJSON
+	}
+
+	return ""
+}
+
+// ollamaKeepAlive maps how long the model stays in memory onto the keep alive of the Ollama: empty for
+// its default and -1 to keep it forever.
+func ollamaKeepAlive(keepAlive time.Duration) string {
+	switch {
+	case keepAlive == 0:
+		return ""
+	case keepAlive < 0:
+		return "-1"
+	}
+
+	return keepAlive.String()
+}
+
+// applyGeneration sets the parameters of the generation on a request to the OpenAI. The context window
+// and the repeat penalty have no equivalent and are ignored.
+func applyGeneration(req *openai.ChatCompletionRequest, generation config.Generation) {
+	if generation.Temperature != nil {
+		req.Temperature = float32(*generation.Temperature)
+
+		// A temperature of 0 is omitted by the client, the smallest one above is sent instead.
+		if req.Temperature == 0 {
+			req.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+
+	if generation.TopP != nil {
+		req.TopP = float32(*generation.TopP)
+
+		if req.TopP == 0 {
+			req.TopP = math.SmallestNonzeroFloat32
+		}
+	}
+
+	req.MaxTokens = generation.MaxTokens
+	req.Seed = generation.Seed
+	req.Stop = generation.Stop
+
+	if generation.Format == formatJSON {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+}
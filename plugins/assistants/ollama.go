@@ -1,6 +1,7 @@
 package assistants
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -12,6 +13,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// pullAttempts is the number of times the download of a missing model is attempted, it resumes where
+// the previous attempt stopped.
+const pullAttempts = 5
+
 // Ollama is an assistant that can chat with you.
 type Ollama struct {
 	// Endpoint is the endpoint of the Ollama.
@@ -20,28 +25,59 @@ type Ollama struct {
 	Chat []ollama.Message
 	// Model is the model of the Ollama.
 	Model string `yaml:"model"`
+	// Summarizer summarizes the long outputs of the tools.
+	Summarizer *Summarizer
+	// SystemPrompt is the template of the system prompt, the default one is used if it is empty.
+	SystemPrompt string
+	// Options are the parameters of the model for the answers to the user, like temperature.
+	Options *ollama.Options
+	// Format is the format of the answers to the user, json or empty for text.
+	Format string
+	// KeepAlive is how long the model stays in memory after a request, empty for the default.
+	KeepAlive string
+	// NoPull makes Setup fail instead of pulling the model when it is missing.
+	NoPull bool
+
+	events
 }
 
 // NewDefaultOllama creates a new Ollama.
 func NewDefaultOllama() *Ollama {
-	return &Ollama{
+	o := &Ollama{
 		Client: ollama.NewOllamaClient("http://localhost:11434"),
 		Chat:   []ollama.Message{},
 		Model:  "adrienbrault/nous-hermes2pro:Q8_0",
 	}
+	o.Summarizer = NewSummarizer(o, config.Summarization{})
+	return o
 }
 
 // NewOllama creates a new Ollama.
 func NewOllama(config *config.Base) *Ollama {
-	return &Ollama{
-		Client: ollama.NewOllamaClient(config.Assistants.Ollama.Endpoint),
+	o := &Ollama{
+		Client: NewOllamaClient(config),
 		Chat:   []ollama.Message{},
 		Model:  config.Assistants.Ollama.Model,
+
+		SystemPrompt: config.Prompts.System,
+		Options:      ollamaOptions(config.Assistants.Generation),
+		Format:       ollamaFormat(config.Assistants.Generation.Format),
+		KeepAlive:    ollamaKeepAlive(config.Assistants.Ollama.KeepAlive),
 	}
+	o.Summarizer = NewSummarizer(o, config.Assistants.Summarization)
+	return o
+}
+
+// NewOllamaClient creates a client for the Ollama of the configuration, with its timeouts.
+func NewOllamaClient(config *config.Base) *ollama.OllamaClient {
+	return ollama.NewOllamaClientWithTimeouts(config.Assistants.Ollama.Endpoint, ollama.Timeouts{
+		Dial:           config.Assistants.Ollama.DialTimeout,
+		ResponseHeader: config.Assistants.Ollama.ResponseHeaderTimeout,
+	})
 }
 
 // processToolCall processes the tool call.
-func (o *Ollama) processToolCall(toolCall string) (string, error) {
+func (o *Ollama) processToolCall(ctx context.Context, toolCall string) (string, error) {
 	// Get only the content between the <tool_call> tags, other text can be ignored
 	toolCall = strings.Split(toolCall, "<tool_call>")[1]
 	toolCall = strings.Split(toolCall, "</tool_call>")[0]
@@ -50,7 +86,7 @@ func (o *Ollama) processToolCall(toolCall string) (string, error) {
 	// Replace ' by " to avoid json unmarshal error
 	toolCall = strings.ReplaceAll(toolCall, "'", "\"")
 
-	log.Debugf("Processing tool call: %s", toolCall)
+	o.logger().Debugf("Processing tool call: %s", toolCall)
 
 	var toolCallJSON map[string]interface{}
 
@@ -85,7 +121,11 @@ func (o *Ollama) processToolCall(toolCall string) (string, error) {
 		userQuery = searchQuery
 	}
 
+	o.emit(Event{Type: EventToolCallStarted, Tool: toolName, Arguments: toolArguments})
+	start := time.Now()
+
 	toolResponse, err := tool.Run(toolArguments, userQuery)
+	o.emitToolCall(toolName, toolArguments, start, err)
 
 	if err != nil {
 		return "", err
@@ -95,22 +135,16 @@ func (o *Ollama) processToolCall(toolCall string) (string, error) {
 	case "string":
 		return toolResponse.Data, nil
 	case "prompt":
-		processedPrompts := make([]string, 0)
+		processedPrompts, err := o.Summarizer.Summarize(ctx, toolResponse.Prompts, userQuery)
 
-		for _, prompt := range toolResponse.Prompts {
-			result, err := o.SendRequestWithnoMemory([]string{fmt.Sprintf("Please summarize and extract the key information from the following text: %s", prompt)})
-			if err != nil {
-				log.Warnf("Error processing prompt: %s", err.Error())
-				continue
-			}
-
-			processedPrompts = append(processedPrompts, result)
+		if err != nil {
+			return "", err
 		}
 
 		processedPrompts = append(processedPrompts, fmt.Sprintf("user query: %s\n NOTE: Be concise, short and specific, and you must answer with the same language as the user query.", userQuery))
 
-		log.Debugf("Tool prompts: %v", processedPrompts)
-		result, err := o.SendRequestWithnoMemory(processedPrompts)
+		o.logger().WithField("tool", toolName).Debugf("Tool prompts: %v", processedPrompts)
+		result, err := o.SendRequestWithnoMemory(ctx, processedPrompts)
 
 		if err != nil {
 			return "", err
@@ -123,7 +157,7 @@ func (o *Ollama) processToolCall(toolCall string) (string, error) {
 }
 
 // SendRequestWithnoMemory is a method that allows the Ollama to chat with you without memory.
-func (o *Ollama) SendRequestWithnoMemory(input []string) (string, error) {
+func (o *Ollama) SendRequestWithnoMemory(ctx context.Context, input []string) (string, error) {
 	messages := []ollama.Message{}
 
 	inputsWithoutLast := input[:len(input)-1]
@@ -141,30 +175,24 @@ func (o *Ollama) SendRequestWithnoMemory(input []string) (string, error) {
 		Content: lastInput,
 	})
 
-	resp, err := o.Client.Chat(&ollama.ChatRequest{
-		Model:    o.Model,
-		Messages: messages,
-	})
+	resp, err := o.Client.Chat(ctx, o.chatRequest(o.Model, messages))
 
 	if err != nil {
 		return "", err
 	}
 
-	log.Debugf("Response: %s", resp.Message.Content)
+	o.logger().Debugf("Response: %s", resp.Message.Content)
 	return resp.Message.Content, nil
 }
 
 // SendRequestWithNoMemoryCustomModel is a method that allows the Ollama to chat with you without memory and with a custom model.
-func (o *Ollama) SendRequestWithNoMemoryCustomModel(input string, model string) (string, error) {
-	resp, err := o.Client.Chat(&ollama.ChatRequest{
-		Model: model,
-		Messages: []ollama.Message{
-			{
-				Role:    "user",
-				Content: input,
-			},
+func (o *Ollama) SendRequestWithNoMemoryCustomModel(ctx context.Context, input string, model string) (string, error) {
+	resp, err := o.Client.Chat(ctx, o.chatRequest(model, []ollama.Message{
+		{
+			Role:    "user",
+			Content: input,
 		},
-	})
+	}))
 
 	if err != nil {
 		return "", err
@@ -174,7 +202,7 @@ func (o *Ollama) SendRequestWithNoMemoryCustomModel(input string, model string)
 }
 
 // SendRequest is a method that allows the Ollama to chat with you.
-func (o *Ollama) SendRequest(input string, callback func(output string, err error)) error {
+func (o *Ollama) SendRequest(ctx context.Context, input string, callback func(output string, err error)) error {
 	if callback == nil {
 		return fmt.Errorf("callback is nil")
 	}
@@ -184,10 +212,11 @@ func (o *Ollama) SendRequest(input string, callback func(output string, err erro
 		Content: input,
 	})
 
-	chanResp, chanErr, err := o.Client.ChatStream(&ollama.ChatRequest{
-		Model:    o.Model,
-		Messages: o.Chat,
-	})
+	// Only the answers to the user follow the parameters of the generation.
+	req := o.chatRequest(o.Model, o.Chat)
+	req.Format, req.Options = o.Format, o.Options
+
+	chanResp, chanErr, err := o.Client.ChatStream(ctx, req)
 
 	if err != nil {
 		callback("", err)
@@ -200,8 +229,20 @@ func (o *Ollama) SendRequest(input string, callback func(output string, err erro
 
 	for inProgress {
 		select {
-		case resp := <-chanResp:
+		case resp, ok := <-chanResp:
+			// The channel is closed before the answer is done when it fails.
+			if !ok {
+				callback("", <-chanErr)
+				return nil
+			}
+
 			if resp.Done {
+				o.emit(Event{Type: EventUsage, Usage: &Usage{
+					PromptTokens:     resp.PromptEvalCount,
+					CompletionTokens: resp.EvalCount,
+					TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+				}})
+
 				callback(resp.Message.Content+"\n", nil)
 				inProgress = false
 				continue
@@ -231,8 +272,8 @@ func (o *Ollama) SendRequest(input string, callback func(output string, err erro
 	})
 
 	if toolMode && strings.Contains(fullContent, "<tool_call>") {
-		log.Debug("Tool call detected")
-		toolCall, err := o.processToolCall(fullContent)
+		o.logger().Debug("Tool call detected")
+		toolCall, err := o.processToolCall(ctx, fullContent)
 
 		if err != nil {
 			o.Chat = o.Chat[:len(o.Chat)-1]
@@ -252,19 +293,55 @@ func (o *Ollama) SendRequest(input string, callback func(output string, err erro
 	return nil
 }
 
-// Setup sets up the Ollama, if something is needed before starting the Ollama.
-func (o *Ollama) Setup() error {
-	_, err := o.Client.ShowModel(&ollama.ShowModelRequest{
+// History returns the messages of the conversation.
+func (o *Ollama) History() []Message {
+	messages := make([]Message, 0, len(o.Chat))
+
+	for _, message := range o.Chat {
+		messages = append(messages, Message{
+			Role:    message.Role,
+			Content: message.Content,
+		})
+	}
+
+	return messages
+}
+
+// SetHistory replaces the messages of the conversation.
+func (o *Ollama) SetHistory(messages []Message) {
+	o.Chat = make([]ollama.Message, 0, len(messages))
+
+	for _, message := range messages {
+		o.Chat = append(o.Chat, ollama.Message{
+			Role:    message.Role,
+			Content: message.Content,
+		})
+	}
+}
+
+// Setup sets up the Ollama, pulling the model if it is missing and NoPull is not set.
+func (o *Ollama) Setup(ctx context.Context) error {
+	_, err := o.Client.ShowModel(ctx, &ollama.ShowModelRequest{
 		Name: o.Model,
 	})
 
-	if err != nil {
-		log.Debugf("Model not found: %v", err)
-		log.Debugf("Pulling model: %v", o.Model)
+	if err != nil && o.NoPull {
+		return fmt.Errorf("the model %s is not available: %w", o.Model, err)
+	}
 
-		_, err := o.Client.PullModel(&ollama.PullModelRequest{
-			Name:   o.Model,
-			Stream: false,
+	if err != nil {
+		o.logger().Debugf("Model not found: %v", err)
+		o.logger().Infof("Pulling model: %v", o.Model)
+
+		err := o.Client.PullModelWithRetry(ctx, &ollama.PullModelRequest{Name: o.Model}, pullAttempts, func(status *ollama.PullModelResponse) error {
+			o.emit(Event{Type: EventPullProgress, Pull: &PullProgress{
+				Model:     o.Model,
+				Status:    status.Status,
+				Digest:    status.Digest,
+				Total:     status.Total,
+				Completed: status.Completed,
+			}})
+			return nil
 		})
 
 		if err != nil {
@@ -272,58 +349,31 @@ func (o *Ollama) Setup() error {
 		}
 	}
 
-	current_time := time.Now().Local()
-
-	toolsJSON, err := tools.GetRepository().DumpToolsJSON()
+	systemPrompt, err := BuildSystemPrompt(o.SystemPrompt)
 
 	if err != nil {
 		return err
 	}
 
 	o.Chat = append(o.Chat, ollama.Message{
-		Role: "system",
-		Content: strings.TrimSpace(fmt.Sprintf(`Today date: %s
-		You are a function-calling AI model named PishIA. You are equipped with function signatures within <tools></tools> XML tags. Your role is to assist with user queries by appropriately calling one or more of these functions, based strictly on provided and valid data:
-		
-		### Available Tools:
-		%s
-		
-		### Instructions:
-		- **Pre-execution Validation**: Execute functions only if all necessary parameters are validated for completeness and correctness. If any required parameter like phone_number is missing or invalid, halt the execution and request the correct data.
-		- **Mandatory Field Verification**: Implement checks to ensure critical fields such as phone_number are never empty. Prompt the user to provide missing information before proceeding.
-		- **Error Messaging**: Provide clear feedback if data is incomplete or invalid. For example, if the phone_number field is empty, immediately respond with "Please provide a valid phone number to complete the reservation."
-		- **Conditional Logic in Tool Calls**: Incorporate logic that prevents function execution if essential parameters are missing or fail to meet validation criteria.
-		- **User Prompt for Missing Information**: If crucial information is missing during a tool call request, explicitly prompt the user to supply the missing data.
-		- **Robust Logging for Incomplete Calls**: Log attempts to execute functions with incomplete data as errors. This helps in identifying and rectifying procedural flaws.
-		- **Continuous Monitoring and Improvement**: Regularly monitor and update validation processes to ensure effectiveness and address new requirements or discovered loopholes.
-		- **Language Consistency**: Always respond in the same language as the user's query to maintain communication consistency.
-		- **Use of Defined Tools Only**: Strictly utilize tools defined within the <tools></tools> XML tags; using undeclared tools is prohibited.
-		- **Function Call Format**: Use the <tool_call></tool_call> XML tags to structure function calls. If you call a function, don't include any other text in the response.
-		- **Tool Call JSON Schema**: Ensure that each function call adheres to the JSON schema provided below.
-		
-		### JSON Schema for Tool Calls:
-		Use the following Pydantic model JSON schema for each tool call:
-		{
-			"properties": {
-				"arguments": {"title": "Arguments", "type": "object"},
-				"name": {"title": "Name", "type": "string"}
-			},
-			"required": ["arguments", "name"],
-			"title": "FunctionCall",
-			"type": "object"
-		}
-
-		For each function call, return a JSON object with the function name and arguments within <tool_call></tool_call> XML tags as follows:
-
-		<tool_call>
-		{"arguments": <args-dict>, "name": <function-name>}
-		</tool_call>
-
-
-		This system prompt is structured to enforce a disciplined approach to function execution, ensuring that only complete and validated data triggers an operation.
-
-		`, current_time.Format("2006-01-02"), toolsJSON)),
+		Role:    "system",
+		Content: systemPrompt,
 	})
 
 	return nil
 }
+
+// chatRequest creates a request to chat with the model with its default parameters, like the requests
+// of the summaries and of the tools.
+func (o *Ollama) chatRequest(model string, messages []ollama.Message) *ollama.ChatRequest {
+	return &ollama.ChatRequest{
+		Model:     model,
+		Messages:  messages,
+		KeepAlive: o.KeepAlive,
+	}
+}
+
+// logger returns the logger of the Ollama, with the name of the assistant.
+func (o *Ollama) logger() *log.Entry {
+	return log.WithField("assistant", "ollama")
+}
@@ -16,32 +16,66 @@ import (
 )
 
 type OpenAI struct {
-	// Client is the client of the OpenAI.
+	// Client is the client of the OpenAI, created by Setup.
 	Client *openai.Client
+	// APIKey is the API key of the OpenAI, or a reference to it resolved by Setup.
+	APIKey string
+	// Endpoint is the endpoint of the OpenAI.
+	Endpoint string
 	// Chat is the chat of the OpenAI.
 	Chat []openai.ChatCompletionMessage
 	// Model is the model of the OpenAI.
 	Model string `yaml:"model"`
+	// Summarizer summarizes the long outputs of the tools.
+	Summarizer *Summarizer
+	// SystemPrompt is the template of the system prompt, the default one is used if it is empty.
+	SystemPrompt string
+	// Generation are the parameters of the generation of the answers to the user.
+	Generation config.Generation
+
+	events
 }
 
 // NewOpenAI creates a new OpenAI.
 func NewOpenAI(config *config.Base) *OpenAI {
-	openaiConfig := openai.DefaultConfig(config.Assistants.OpenAI.APIKey)
-	openaiConfig.BaseURL = config.Assistants.OpenAI.Endpoint
-	return &OpenAI{
-		Client: openai.NewClientWithConfig(openaiConfig),
-		Chat:   make([]openai.ChatCompletionMessage, 0),
-		Model:  config.Assistants.OpenAI.Model,
+	o := &OpenAI{
+		APIKey:   config.Assistants.OpenAI.APIKey,
+		Endpoint: config.Assistants.OpenAI.Endpoint,
+		Chat:     make([]openai.ChatCompletionMessage, 0),
+		Model:    config.Assistants.OpenAI.Model,
+
+		SystemPrompt: config.Prompts.System,
+		Generation:   config.Assistants.Generation,
 	}
+	o.Summarizer = NewSummarizer(o, config.Assistants.Summarization)
+	return o
 }
 
-func (o *OpenAI) processToolCall(toolCall string) (string, error) {
+// NewOpenAIClient creates a client for the OpenAI of the configuration, resolving its API key.
+func NewOpenAIClient(config *config.Base) (*openai.Client, error) {
+	return newOpenAIClient(config.Assistants.OpenAI.APIKey, config.Assistants.OpenAI.Endpoint)
+}
+
+// newOpenAIClient creates a client for the endpoint, resolving the API key if it is a reference.
+func newOpenAIClient(apiKey string, endpoint string) (*openai.Client, error) {
+	apiKey, err := config.ResolveSecret(apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("resolving assistants.openai.api_key: %w", err)
+	}
+
+	openaiConfig := openai.DefaultConfig(apiKey)
+	openaiConfig.BaseURL = endpoint
+
+	return openai.NewClientWithConfig(openaiConfig), nil
+}
+
+func (o *OpenAI) processToolCall(ctx context.Context, toolCall string) (string, error) {
 	// Get only the content between the <tool_call> tags, other text can be ignored
 	toolCall = strings.Split(toolCall, "<tool_call>")[1]
 	toolCall = strings.Split(toolCall, "</tool_call>")[0]
 	toolCall = strings.TrimSpace(toolCall)
 
-	log.Debugf("Processing tool call: %s", toolCall)
+	o.logger().Debugf("Processing tool call: %s", toolCall)
 
 	var toolCallJSON map[string]interface{}
 
@@ -77,7 +111,11 @@ func (o *OpenAI) processToolCall(toolCall string) (string, error) {
 		userQuery = searchQuery
 	}
 
+	o.emit(Event{Type: EventToolCallStarted, Tool: toolName, Arguments: toolArguments})
+	start := time.Now()
+
 	toolResponse, err := tool.Run(toolArguments, userQuery)
+	o.emitToolCall(toolName, toolArguments, start, err)
 
 	if err != nil {
 		return "", err
@@ -87,32 +125,16 @@ func (o *OpenAI) processToolCall(toolCall string) (string, error) {
 	case "string":
 		return toolResponse.Data, nil
 	case "prompt":
-		processedPrompts := make([]string, len(toolResponse.Prompts))
-		errChan := make(chan error, len(toolResponse.Prompts))
-
-		for i, prompt := range toolResponse.Prompts {
-			go func(i int, prompt string) {
-				result, err := o.SendRequestWithnoMemory([]string{fmt.Sprintf("Please summarize and extract the key information from the following text: %s", prompt)})
-				if err != nil {
-					errChan <- err
-					return
-				}
-				processedPrompts[i] = result
-				errChan <- nil
-			}(i, prompt)
-		}
+		processedPrompts, err := o.Summarizer.Summarize(ctx, toolResponse.Prompts, userQuery)
 
-		for range toolResponse.Prompts {
-			err := <-errChan
-			if err != nil {
-				log.Warnf("Error processing prompt: %s", err.Error())
-			}
+		if err != nil {
+			return "", err
 		}
 
 		processedPrompts = append(processedPrompts, fmt.Sprintf("user query: %s\n NOTE: Be concise, short and specific, and you must answer with the same language as the user query.", userQuery))
 
-		log.Debugf("Tool prompts: %v", processedPrompts)
-		result, err := o.SendRequestWithnoMemory(processedPrompts)
+		o.logger().WithField("tool", toolName).Debugf("Tool prompts: %v", processedPrompts)
+		result, err := o.SendRequestWithnoMemory(ctx, processedPrompts)
 
 		if err != nil {
 			return "", err
@@ -125,7 +147,7 @@ func (o *OpenAI) processToolCall(toolCall string) (string, error) {
 }
 
 // SendRequestWithnoMemoryAndModel sends a request to the OpenAI without memory and with a specific model.
-func (o *OpenAI) SendRequestWithnoMemoryAndModel(prompts []string, model string) (string, error) {
+func (o *OpenAI) SendRequestWithnoMemoryAndModel(ctx context.Context, prompts []string, model string) (string, error) {
 	messages := make([]openai.ChatCompletionMessage, 0)
 
 	for _, prompt := range prompts {
@@ -135,12 +157,9 @@ func (o *OpenAI) SendRequestWithnoMemoryAndModel(prompts []string, model string)
 		})
 	}
 
-	req := openai.ChatCompletionRequest{
-		Model:    model,
-		Messages: messages,
-	}
+	req := o.chatRequest(model, messages)
 
-	resp, err := o.Client.CreateChatCompletion(context.Background(), req)
+	resp, err := o.Client.CreateChatCompletion(ctx, req)
 
 	if err != nil {
 		return "", err
@@ -150,7 +169,7 @@ func (o *OpenAI) SendRequestWithnoMemoryAndModel(prompts []string, model string)
 }
 
 // SendRequestWithnoMemory sends a request to the OpenAI without memory.
-func (o *OpenAI) SendRequestWithnoMemory(prompts []string) (string, error) {
+func (o *OpenAI) SendRequestWithnoMemory(ctx context.Context, prompts []string) (string, error) {
 	messages := make([]openai.ChatCompletionMessage, 0)
 
 	for _, prompt := range prompts {
@@ -163,13 +182,9 @@ func (o *OpenAI) SendRequestWithnoMemory(prompts []string) (string, error) {
 		})
 	}
 
-	req := openai.ChatCompletionRequest{
-		Model:       o.Model,
-		Messages:    messages,
-		Temperature: 0,
-	}
+	req := o.chatRequest(o.Model, messages)
 
-	resp, err := o.Client.CreateChatCompletion(context.Background(), req)
+	resp, err := o.Client.CreateChatCompletion(ctx, req)
 
 	if err != nil {
 		return "", err
@@ -179,19 +194,20 @@ func (o *OpenAI) SendRequestWithnoMemory(prompts []string) (string, error) {
 }
 
 // SendRequest sends a request to the OpenAI.
-func (o *OpenAI) SendRequest(prompt string, callback func(output string, err error)) error {
+func (o *OpenAI) SendRequest(ctx context.Context, prompt string, callback func(output string, err error)) error {
 	o.Chat = append(o.Chat, openai.ChatCompletionMessage{
 		Role:    "user",
 		Content: prompt,
 	})
 
-	req := openai.ChatCompletionRequest{
-		Model:       o.Model,
-		Messages:    o.Chat,
-		Temperature: 0,
+	// Only the answers to the user follow the parameters of the generation.
+	req := o.chatRequest(o.Model, o.Chat)
+	applyGeneration(&req, o.Generation)
+	req.StreamOptions = &openai.StreamOptions{
+		IncludeUsage: true,
 	}
 
-	stream, err := o.Client.CreateChatCompletionStream(context.Background(), req)
+	stream, err := o.Client.CreateChatCompletionStream(ctx, req)
 
 	if err != nil {
 		callback("", err)
@@ -214,7 +230,20 @@ func (o *OpenAI) SendRequest(prompt string, callback func(output string, err err
 			return nil
 		}
 
-		if len(response.Choices) > 0 && len(response.Choices[0].Delta.Content) > 0 && response.Choices[0].Delta.Content[0] == '<' {
+		if response.Usage != nil {
+			o.emit(Event{Type: EventUsage, Usage: &Usage{
+				PromptTokens:     response.Usage.PromptTokens,
+				CompletionTokens: response.Usage.CompletionTokens,
+				TotalTokens:      response.Usage.TotalTokens,
+			}})
+		}
+
+		// The last chunk only has the usage.
+		if len(response.Choices) == 0 {
+			continue
+		}
+
+		if len(response.Choices[0].Delta.Content) > 0 && response.Choices[0].Delta.Content[0] == '<' {
 			toolMode = true
 		}
 
@@ -233,8 +262,8 @@ func (o *OpenAI) SendRequest(prompt string, callback func(output string, err err
 	})
 
 	if toolMode && strings.Contains(fullContent, "<tool_call>") {
-		log.Debug("Tool call detected")
-		toolCall, err := o.processToolCall(fullContent)
+		o.logger().Debug("Tool call detected")
+		toolCall, err := o.processToolCall(ctx, fullContent)
 
 		if err != nil {
 			o.Chat = o.Chat[:len(o.Chat)-1]
@@ -254,60 +283,64 @@ func (o *OpenAI) SendRequest(prompt string, callback func(output string, err err
 	return nil
 }
 
-// Setup sets up the OpenAI assistant.
-func (o *OpenAI) Setup() error {
-	current_time := time.Now().Local()
-
-	toolsJSON, err := tools.GetRepository().DumpToolsJSON()
+// History returns the messages of the conversation.
+func (o *OpenAI) History() []Message {
+	messages := make([]Message, 0, len(o.Chat))
 
-	if err != nil {
-		return err
+	for _, message := range o.Chat {
+		messages = append(messages, Message{
+			Role:    message.Role,
+			Content: message.Content,
+		})
 	}
 
-	o.Chat = append(o.Chat, openai.ChatCompletionMessage{
-		Role: "system",
-		Content: strings.TrimSpace(fmt.Sprintf(`Today date: %s
-		You are a function-calling AI model named PishIA. You are equipped with function signatures within <tools></tools> XML tags. Your role is to assist with user queries by appropriately calling one or more of these functions, based strictly on provided and valid data:
-		
-		### Available Tools:
-		%s
-		
-		### Instructions:
-		- **Pre-execution Validation**: Execute functions only if all necessary parameters are validated for completeness and correctness. If any required parameter like phone_number is missing or invalid, halt the execution and request the correct data.
-		- **Mandatory Field Verification**: Implement checks to ensure critical fields such as phone_number are never empty. Prompt the user to provide missing information before proceeding.
-		- **Error Messaging**: Provide clear feedback if data is incomplete or invalid. For example, if the phone_number field is empty, immediately respond with "Please provide a valid phone number to complete the reservation."
-		- **Conditional Logic in Tool Calls**: Incorporate logic that prevents function execution if essential parameters are missing or fail to meet validation criteria.
-		- **User Prompt for Missing Information**: If crucial information is missing during a tool call request, explicitly prompt the user to supply the missing data.
-		- **Robust Logging for Incomplete Calls**: Log attempts to execute functions with incomplete data as errors. This helps in identifying and rectifying procedural flaws.
-		- **Continuous Monitoring and Improvement**: Regularly monitor and update validation processes to ensure effectiveness and address new requirements or discovered loopholes.
-		- **Language Consistency**: Always respond in the same language as the user's query to maintain communication consistency.
-		- **Use of Defined Tools Only**: Strictly utilize tools defined within the <tools></tools> XML tags; using undeclared tools is prohibited.
-		- **Function Call Format**: Use the <tool_call></tool_call> XML tags to structure function calls. If you call a function, don't include any other text in the response.
-		- **Tool Call JSON Schema**: Ensure that each function call adheres to the JSON schema provided below.
-		
-		### JSON Schema for Tool Calls:
-		Use the following Pydantic model JSON schema for each tool call:
-		{
-			"properties": {
-				"arguments": {"title": "Arguments", "type": "object"},
-				"name": {"title": "Name", "type": "string"}
-			},
-			"required": ["arguments", "name"],
-			"title": "FunctionCall",
-			"type": "object"
-		}
+	return messages
+}
+
+// SetHistory replaces the messages of the conversation.
+func (o *OpenAI) SetHistory(messages []Message) {
+	o.Chat = make([]openai.ChatCompletionMessage, 0, len(messages))
 
-		For each function call, return a JSON object with the function name and arguments within <tool_call></tool_call> XML tags as follows:
+	for _, message := range messages {
+		o.Chat = append(o.Chat, openai.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		})
+	}
+}
 
-		<tool_call>
-		{"arguments": <args-dict>, "name": <function-name>}
-		</tool_call>
+// Setup sets up the OpenAI assistant.
+func (o *OpenAI) Setup(ctx context.Context) error {
+	client, err := newOpenAIClient(o.APIKey, o.Endpoint)
+	if err != nil {
+		return err
+	}
+	o.Client = client
 
+	systemPrompt, err := BuildSystemPrompt(o.SystemPrompt)
 
-		This system prompt is structured to enforce a disciplined approach to function execution, ensuring that only complete and validated data triggers an operation.
+	if err != nil {
+		return err
+	}
 
-		`, current_time.Format("2006-01-02"), toolsJSON)),
+	o.Chat = append(o.Chat, openai.ChatCompletionMessage{
+		Role:    "system",
+		Content: systemPrompt,
 	})
 
 	return nil
 }
+
+// chatRequest creates a request to chat with the model with its default parameters, like the requests
+// of the summaries and of the tools.
+func (o *OpenAI) chatRequest(model string, messages []openai.ChatCompletionMessage) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+	}
+}
+
+// logger returns the logger of the OpenAI, with the name of the assistant.
+func (o *OpenAI) logger() *log.Entry {
+	return log.WithField("assistant", "openai")
+}
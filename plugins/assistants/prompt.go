@@ -0,0 +1,105 @@
+package assistants
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Pishia-IA/core/plugins/tools"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultSystemPrompt is the system prompt used when none is configured.
+const defaultSystemPrompt = `Today date: {{.Date}}
+		You are a function-calling AI model named PishIA. You are equipped with function signatures within <tools></tools> XML tags. Your role is to assist with user queries by appropriately calling one or more of these functions, based strictly on provided and valid data:
+		
+		### Available Tools:
+		{{.Tools}}
+		
+		### Instructions:
+		- **Pre-execution Validation**: Execute functions only if all necessary parameters are validated for completeness and correctness. If any required parameter like phone_number is missing or invalid, halt the execution and request the correct data.
+		- **Mandatory Field Verification**: Implement checks to ensure critical fields such as phone_number are never empty. Prompt the user to provide missing information before proceeding.
+		- **Error Messaging**: Provide clear feedback if data is incomplete or invalid. For example, if the phone_number field is empty, immediately respond with "Please provide a valid phone number to complete the reservation."
+		- **Conditional Logic in Tool Calls**: Incorporate logic that prevents function execution if essential parameters are missing or fail to meet validation criteria.
+		- **User Prompt for Missing Information**: If crucial information is missing during a tool call request, explicitly prompt the user to supply the missing data.
+		- **Robust Logging for Incomplete Calls**: Log attempts to execute functions with incomplete data as errors. This helps in identifying and rectifying procedural flaws.
+		- **Continuous Monitoring and Improvement**: Regularly monitor and update validation processes to ensure effectiveness and address new requirements or discovered loopholes.
+		- **Language Consistency**: Always respond in the same language as the user's query to maintain communication consistency.
+		- **Use of Defined Tools Only**: Strictly utilize tools defined within the <tools></tools> XML tags; using undeclared tools is prohibited.
+		- **Function Call Format**: Use the <tool_call></tool_call> XML tags to structure function calls. If you call a function, don't include any other text in the response.
+		- **Tool Call JSON Schema**: Ensure that each function call adheres to the JSON schema provided below.
+		
+		### JSON Schema for Tool Calls:
+		Use the following Pydantic model JSON schema for each tool call:
+		{
+			"properties": {
+				"arguments": {"title": "Arguments", "type": "object"},
+				"name": {"title": "Name", "type": "string"}
+			},
+			"required": ["arguments", "name"],
+			"title": "FunctionCall",
+			"type": "object"
+		}
+
+		For each function call, return a JSON object with the function name and arguments within <tool_call></tool_call> XML tags as follows:
+
+		<tool_call>
+		{"arguments": <args-dict>, "name": <function-name>}
+		</tool_call>
+
+
+		This system prompt is structured to enforce a disciplined approach to function execution, ensuring that only complete and validated data triggers an operation.
+
+		`
+
+// systemPromptData is the data available to the system prompt template.
+type systemPromptData struct {
+	// Date is the current date.
+	Date string
+	// Tools is the JSON description of the tools.
+	Tools string
+}
+
+// BuildSystemPrompt renders the system prompt template, or the default one if it is empty.
+// A custom prompt that is not a valid template, like one with JSON or Handlebars braces, is used as it is.
+func BuildSystemPrompt(systemPrompt string) (string, error) {
+	toolsJSON, err := tools.GetRepository().DumpToolsJSON()
+	if err != nil {
+		return "", err
+	}
+
+	data := systemPromptData{
+		Date:  time.Now().Local().Format("2006-01-02"),
+		Tools: toolsJSON,
+	}
+
+	if strings.TrimSpace(systemPrompt) == "" {
+		return renderSystemPrompt(defaultSystemPrompt, data)
+	}
+
+	prompt, err := renderSystemPrompt(systemPrompt, data)
+	if err != nil {
+		log.Debugf("The system prompt is not a template, using it as it is: %s", err.Error())
+		return strings.TrimSpace(systemPrompt), nil
+	}
+
+	return prompt, nil
+}
+
+// renderSystemPrompt executes the template of the system prompt with the data.
+func renderSystemPrompt(systemPrompt string, data systemPromptData) (string, error) {
+	tmpl, err := template.New("system").Parse(systemPrompt)
+	if err != nil {
+		return "", err
+	}
+
+	var prompt bytes.Buffer
+
+	err = tmpl.Execute(&prompt, data)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(prompt.String()), nil
+}
@@ -0,0 +1,52 @@
+package assistants
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pishia-IA/core/plugins/tools"
+)
+
+func TestBuildSystemPrompt(t *testing.T) {
+	previous := tools.GetRepository()
+	tools.SetRepository(tools.NewToolRepository())
+	t.Cleanup(func() { tools.SetRepository(previous) })
+
+	date := time.Now().Local().Format("2006-01-02")
+
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"plain text", "Be brief.", "Be brief."},
+		{"template", "Today is {{.Date}}.", "Today is " + date + "."},
+		{"json", `Answer with {{"name": "value"}}.`, `Answer with {{"name": "value"}}.`},
+		{"handlebars", "Hello {{user}}, be brief.", "Hello {{user}}, be brief."},
+		{"unknown field", "Hello {{.User}}.", "Hello {{.User}}."},
+		{"unclosed action", "Use {{ in the code.", "Use {{ in the code."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := BuildSystemPrompt(test.prompt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("BuildSystemPrompt(%q) = %q, want %q", test.prompt, got, test.want)
+			}
+		})
+	}
+
+	prompt, err := BuildSystemPrompt("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(prompt, "Today date: "+date) || strings.Contains(prompt, "{{") {
+		t.Errorf("got default prompt %q, want the rendered default template", prompt)
+	}
+}
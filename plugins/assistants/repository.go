@@ -1,19 +1,49 @@
 package assistants
 
-import "github.com/Pishia-IA/core/config"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Pishia-IA/core/config"
+)
 
 var (
 	// repository is a repository that contains all the assistants.
 	repository *AssistantRepository
 	// defaultAssistant is the default assistant.
 	defaultAssistant string
+	// mu protects repository and defaultAssistant, they are replaced when the configuration is reloaded.
+	mu sync.RWMutex
+	// constructors are the constructors of the available assistant plugins.
+	constructors = map[string]func(*config.Base) Assistant{
+		"ollama": func(config *config.Base) Assistant { return NewOllama(config) },
+		"openai": func(config *config.Base) Assistant { return NewOpenAI(config) },
+	}
 )
 
+// Message is a message of a conversation, independent of the assistant plugin.
+type Message struct {
+	// Role is the role of the author of the message: system, user or assistant.
+	Role string `json:"role"`
+	// Content is the content of the message.
+	Content string `json:"content"`
+}
+
 type Assistant interface {
-	// SendRequest is a method that allows the assistant to chat with you.
-	SendRequest(input string, callback func(output string, err error)) error
-	// Setup sets up the assistant, if something is needed before starting the assistant.
-	Setup() error
+	// SendRequest is a method that allows the assistant to chat with you. The answer stops when the
+	// context is canceled.
+	SendRequest(ctx context.Context, input string, callback func(output string, err error)) error
+	// Setup sets up the assistant, if something is needed before starting the assistant, like pulling
+	// its model. It stops when the context is canceled.
+	Setup(ctx context.Context) error
+	// History returns the messages of the conversation, the system prompt included.
+	History() []Message
+	// SetHistory replaces the messages of the conversation.
+	SetHistory(messages []Message)
+	// SetEventHandler sets the handler that receives the events of the requests.
+	SetEventHandler(handler EventHandler)
 }
 
 // AssistantRepository is a repository that contains all the assistants.
@@ -42,11 +72,20 @@ func (r *AssistantRepository) Get(name string) (Assistant, bool) {
 
 // GetRepository gets the repository.
 func GetRepository() *AssistantRepository {
+	mu.RLock()
+	defer mu.RUnlock()
 	return repository
 }
 
 // GetDefaultAssistant gets the default assistant.
 func GetDefaultAssistant() Assistant {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if repository == nil {
+		return nil
+	}
+
 	assistant, ok := repository.Get(defaultAssistant)
 	if !ok {
 		return nil
@@ -54,11 +93,92 @@ func GetDefaultAssistant() Assistant {
 	return assistant
 }
 
+// Plugins returns the names of the available assistant plugins.
+func Plugins() []string {
+	plugins := make([]string, 0, len(constructors))
+
+	for name := range constructors {
+		plugins = append(plugins, name)
+	}
+
+	sort.Strings(plugins)
+	return plugins
+}
+
+// New creates an assistant of the plugin, independent of the repository. Each assistant has its own
+// conversation.
+func New(config *config.Base, plugin string) (Assistant, error) {
+	constructor, ok := constructors[plugin]
+	if !ok {
+		return nil, fmt.Errorf("unknown assistant plugin %q", plugin)
+	}
+
+	return constructor(config), nil
+}
+
 // StartAssistants starts the assistants.
 func StartAssistants(config *config.Base) {
-	repository = NewAssistantRepository()
-	repository.Register("ollama", NewOllama(config))
-	repository.Register("openai", NewOpenAI(config))
+	mu.Lock()
+	defer mu.Unlock()
+
+	repository = newRepository(config)
+	defaultAssistant = config.Assistants.Plugin
+}
 
+// Reload rebuilds the assistants with a new configuration. The new default assistant is set up and
+// continues the conversation of the current one. If it can't be set up, the current assistants are kept.
+func Reload(ctx context.Context, config *config.Base) error {
+	newRepository := newRepository(config)
+
+	assistant, ok := newRepository.Get(config.Assistants.Plugin)
+	if !ok {
+		return fmt.Errorf("unknown assistant plugin %q", config.Assistants.Plugin)
+	}
+
+	err := assistant.Setup(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current := GetDefaultAssistant(); current != nil {
+		ContinueConversation(current, assistant)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	repository = newRepository
 	defaultAssistant = config.Assistants.Plugin
+	return nil
+}
+
+// ContinueConversation copies the conversation of an assistant to another one, the system prompt of the
+// target is kept.
+func ContinueConversation(from Assistant, to Assistant) {
+	messages := make([]Message, 0)
+
+	for _, message := range to.History() {
+		if message.Role == "system" {
+			messages = append(messages, message)
+		}
+	}
+
+	for _, message := range from.History() {
+		if message.Role != "system" {
+			messages = append(messages, message)
+		}
+	}
+
+	to.SetHistory(messages)
+}
+
+// newRepository creates a repository with all the assistant plugins.
+func newRepository(config *config.Base) *AssistantRepository {
+	repository := NewAssistantRepository()
+
+	for name, constructor := range constructors {
+		repository.Register(name, constructor(config))
+	}
+
+	return repository
 }
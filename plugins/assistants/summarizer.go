@@ -0,0 +1,262 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Pishia-IA/core/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultChunkSize is the default maximum number of tokens of each chunk.
+	DefaultChunkSize = 2048
+	// DefaultConcurrency is the default number of chunks summarized at the same time.
+	DefaultConcurrency = 2
+	// charsPerToken is the approximate number of characters per token, used to estimate the size of a text.
+	charsPerToken = 4
+	// minChunkContent is the minimum number of tokens of the text of a chunk, when the prompt and the user
+	// query take most of the chunk.
+	minChunkContent = 64
+	// maxReduceRounds is the maximum number of times the summaries are combined, the model may not be
+	// able to shorten them.
+	maxReduceRounds = 4
+
+	// mapPrompt asks to summarize a chunk, with the user query and the chunk.
+	mapPrompt = "Please summarize and extract the key information from the following text, focusing on what is relevant for the user query.\nUser query: %s\nText: %s"
+	// reducePrompt asks to combine summaries, with the user query and the summaries.
+	reducePrompt = "Please combine the following partial summaries into a single summary, keeping the key information relevant for the user query.\nUser query: %s\nSummaries:\n%s"
+)
+
+// Completer is an assistant that can answer a request without memory.
+type Completer interface {
+	// SendRequestWithnoMemory sends the prompts to the model without using the chat history.
+	SendRequestWithnoMemory(ctx context.Context, input []string) (string, error)
+}
+
+// Summarizer summarizes long tool outputs, splitting them into chunks that fit in the context
+// window of the model, summarizing each chunk (map) and combining the summaries (reduce).
+type Summarizer struct {
+	// Completer is the assistant used to summarize.
+	Completer Completer
+	// ChunkSize is the maximum number of tokens of each chunk.
+	ChunkSize int
+	// Concurrency is the maximum number of chunks summarized at the same time.
+	Concurrency int
+}
+
+// NewSummarizer creates a new Summarizer.
+func NewSummarizer(completer Completer, config config.Summarization) *Summarizer {
+	summarizer := &Summarizer{
+		Completer:   completer,
+		ChunkSize:   config.ChunkSize,
+		Concurrency: config.Concurrency,
+	}
+
+	if summarizer.ChunkSize <= 0 {
+		summarizer.ChunkSize = DefaultChunkSize
+	}
+
+	if summarizer.Concurrency <= 0 {
+		summarizer.Concurrency = DefaultConcurrency
+	}
+
+	return summarizer
+}
+
+// Summarize summarizes the documents with regard to the user query. Every request, its prompt and the
+// user query included, fits in a chunk. It returns a list of summaries that fits, all together, in a
+// single chunk, unless the model can't shorten them enough.
+func (s *Summarizer) Summarize(ctx context.Context, documents []string, userQuery string) ([]string, error) {
+	chunks := make([]string, 0)
+	mapBudget := s.contentBudget(mapPrompt, userQuery)
+
+	for _, document := range documents {
+		chunks = append(chunks, SplitIntoChunks(document, mapBudget)...)
+	}
+
+	if len(chunks) == 0 {
+		return []string{}, nil
+	}
+
+	log.Debugf("Summarizing %d documents in %d chunks", len(documents), len(chunks))
+
+	summaries, err := s.mapChunks(ctx, chunks, func(chunk string) string {
+		return fmt.Sprintf(mapPrompt, userQuery, chunk)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	reduceBudget := s.contentBudget(reducePrompt, userQuery)
+
+	for round := 0; round < maxReduceRounds && EstimateTokens(strings.Join(summaries, "\n\n")) > s.ChunkSize; round++ {
+		// A summary bigger than a group is split, so that every request fits.
+		pieces := make([]string, 0, len(summaries))
+		for _, summary := range summaries {
+			pieces = append(pieces, SplitIntoChunks(summary, reduceBudget)...)
+		}
+
+		groups := groupChunks(pieces, reduceBudget)
+
+		log.Debugf("Reducing %d summaries in %d groups", len(summaries), len(groups))
+
+		summaries, err = s.mapChunks(ctx, groups, func(group string) string {
+			return fmt.Sprintf(reducePrompt, userQuery, group)
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return summaries, nil
+}
+
+// contentBudget returns the number of tokens of a chunk left for its text once the prompt and the user
+// query are added, at least minChunkContent.
+func (s *Summarizer) contentBudget(prompt string, userQuery string) int {
+	budget := s.ChunkSize - EstimateTokens(fmt.Sprintf(prompt, userQuery, ""))
+	if budget < minChunkContent {
+		return minChunkContent
+	}
+
+	return budget
+}
+
+// mapChunks sends each chunk to the model, with at most Concurrency requests at the same time.
+// Chunks that fail are skipped, an error is only returned if all of them fail.
+func (s *Summarizer) mapChunks(ctx context.Context, chunks []string, prompt func(string) string) ([]string, error) {
+	results := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	semaphore := make(chan struct{}, s.Concurrency)
+
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i], errs[i] = s.Completer.SendRequestWithnoMemory(ctx, []string{prompt(chunk)})
+		}(i, chunk)
+	}
+
+	wg.Wait()
+
+	summaries := make([]string, 0, len(chunks))
+	var lastErr error
+
+	for i, result := range results {
+		if errs[i] != nil {
+			log.Warnf("Error processing prompt: %s", errs[i].Error())
+			lastErr = errs[i]
+			continue
+		}
+
+		if strings.TrimSpace(result) == "" {
+			continue
+		}
+
+		summaries = append(summaries, result)
+	}
+
+	if len(summaries) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return summaries, nil
+}
+
+// EstimateTokens estimates the number of tokens of a text.
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// SplitIntoChunks splits a text into chunks of at most maxTokens tokens. It splits on paragraphs
+// first, and on words when a paragraph doesn't fit in a chunk.
+func SplitIntoChunks(text string, maxTokens int) []string {
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return []string{}
+	}
+
+	if EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	pieces := make([]string, 0)
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+
+		if paragraph == "" {
+			continue
+		}
+
+		if EstimateTokens(paragraph) <= maxTokens {
+			pieces = append(pieces, paragraph)
+			continue
+		}
+
+		pieces = append(pieces, splitWords(paragraph, maxTokens)...)
+	}
+
+	return groupChunks(pieces, maxTokens)
+}
+
+// splitWords splits a paragraph into pieces of at most maxTokens tokens on word boundaries.
+func splitWords(paragraph string, maxTokens int) []string {
+	pieces := make([]string, 0)
+	var current strings.Builder
+
+	for _, word := range strings.Fields(paragraph) {
+		if current.Len() > 0 && EstimateTokens(current.String()+" "+word) > maxTokens {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+
+		if current.Len() > 0 {
+			current.WriteString(" ")
+		}
+
+		current.WriteString(word)
+	}
+
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+
+	return pieces
+}
+
+// groupChunks joins consecutive pieces while the result fits in maxTokens tokens.
+func groupChunks(pieces []string, maxTokens int) []string {
+	groups := make([]string, 0)
+	current := ""
+
+	for _, piece := range pieces {
+		if current != "" && EstimateTokens(current+"\n\n"+piece) > maxTokens {
+			groups = append(groups, current)
+			current = ""
+		}
+
+		if current != "" {
+			current += "\n\n"
+		}
+
+		current += piece
+	}
+
+	if current != "" {
+		groups = append(groups, current)
+	}
+
+	return groups
+}
@@ -0,0 +1,140 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+)
+
+// words returns a text of n words of 4 characters separated by spaces, about n+1 tokens.
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+func TestSplitIntoChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{"empty", "  \n\n ", 10, []string{}},
+		{"fits", "short text", 10, []string{"short text"}},
+		{"groups the paragraphs", "aaaa\n\nbbbb\n\ncccc", 3, []string{"aaaa\n\nbbbb", "cccc"}},
+		{"skips the empty paragraphs", "aaaa\n\n\n\nbbbb", 1, []string{"aaaa", "bbbb"}},
+		{"splits a long paragraph on words", "aaa bbb ccc ddd", 2, []string{"aaa bbb", "ccc ddd"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SplitIntoChunks(test.text, test.maxTokens)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SplitIntoChunks(%q, %d) = %q, want %q", test.text, test.maxTokens, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitIntoChunksFits(t *testing.T) {
+	text := words(300) + "\n\n" + words(20) + "\n\n" + words(150)
+
+	for _, chunk := range SplitIntoChunks(text, 50) {
+		if tokens := EstimateTokens(chunk); tokens > 50 {
+			t.Errorf("got a chunk of %d tokens, want at most 50", tokens)
+		}
+	}
+}
+
+func TestGroupChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		pieces    []string
+		maxTokens int
+		want      []string
+	}{
+		{"none", nil, 10, []string{}},
+		{"all fit", []string{"aaaa", "bbbb"}, 10, []string{"aaaa\n\nbbbb"}},
+		{"one per group", []string{"aaaa", "bbbb"}, 2, []string{"aaaa", "bbbb"}},
+		{"keeps a piece too big", []string{"aaaa", words(10), "bbbb"}, 3, []string{"aaaa", words(10), "bbbb"}},
+		{"groups consecutive pieces", []string{"aaaa", "bbbb", "cccc", "dddd"}, 3, []string{"aaaa\n\nbbbb", "cccc\n\ndddd"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := groupChunks(test.pieces, test.maxTokens)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("groupChunks(%q, %d) = %q, want %q", test.pieces, test.maxTokens, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name      string
+		documents []string
+		query     string
+		// summary is the answer of the model to the map prompts, the reduce prompts get short.
+		summary string
+		reduce  bool
+	}{
+		{"single chunk", []string{words(20)}, "what?", "short", false},
+		{"several chunks", []string{words(200), words(100)}, "what?", "short", false},
+		{"long query", []string{words(200)}, words(60), "short", false},
+		{"hierarchical reduce", []string{words(3000)}, "what?", words(9), true},
+		{"summaries over budget", []string{words(400)}, "what?", words(200), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, cfg := testConfig(t, "llama3")
+			server.SetReply(func(model string, messages []ollama.Message) string {
+				if strings.HasPrefix(messages[len(messages)-1].Content, "Please combine") {
+					return "short"
+				}
+
+				return test.summary
+			})
+
+			summarizer := NewSummarizer(NewOllama(cfg), config.Summarization{ChunkSize: 150, Concurrency: 2})
+
+			summaries, err := summarizer.Summarize(context.Background(), test.documents, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tokens := EstimateTokens(strings.Join(summaries, "\n\n")); len(summaries) == 0 || tokens > 150 {
+				t.Errorf("got %d summaries of %d tokens, want at most 150 tokens", len(summaries), tokens)
+			}
+
+			reduced := false
+			for _, request := range server.Requests() {
+				var chat ollama.ChatRequest
+				if err := json.Unmarshal(request.Body, &chat); err != nil {
+					t.Fatal(err)
+				}
+
+				prompt := chat.Messages[len(chat.Messages)-1].Content
+				reduced = reduced || strings.HasPrefix(prompt, "Please combine")
+
+				// When the prompt takes most of the chunk, its text is kept at minChunkContent tokens.
+				overhead := max(EstimateTokens(fmt.Sprintf(mapPrompt, test.query, "")), EstimateTokens(fmt.Sprintf(reducePrompt, test.query, "")))
+				budget := max(150, overhead+minChunkContent)
+
+				if tokens := EstimateTokens(prompt); tokens > budget {
+					t.Errorf("got a prompt of %d tokens, want at most %d", tokens, budget)
+				}
+			}
+
+			if reduced != test.reduce {
+				t.Errorf("got reduce %v, want %v", reduced, test.reduce)
+			}
+		})
+	}
+}
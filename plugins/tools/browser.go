@@ -1,50 +1,92 @@
 package tools
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Pishia-IA/core/config"
 
 	"github.com/gelembjuk/articletext"
-
-	"github.com/sap-nocops/duckduckgogo/client"
 )
 
+// browserLog is the logger of the browser tool.
+var browserLog = log.WithField("tool", "browser")
+
 type Browser struct {
 	httpClient *http.Client
+	// maxResults is the maximum number of search results read.
+	maxResults int
+	// timeout is the timeout to read a page.
+	timeout time.Duration
 }
 
 func NewBrowser(config *config.Base) *Browser {
-	return &Browser{
-		httpClient: &http.Client{},
+	browser := &Browser{
+		httpClient: NewHTTPClient(config.Tool.HTTP),
+		maxResults: config.Tool.Browser.MaxResults,
+		timeout:    config.Tool.Browser.Timeout,
+	}
+
+	if browser.maxResults <= 0 {
+		browser.maxResults = MAX_RESULTS_DUCK_DUCK_GO
 	}
+
+	if browser.timeout <= 0 {
+		browser.timeout = DefaultReadTimeout
+	}
+
+	return browser
 }
 
 const MAX_RESULTS_DUCK_DUCK_GO = 3
 
 func (c *Browser) Run(params map[string]interface{}, userQuery string) (*ToolResponse, error) {
 	var urlsToOpen []string
+	// snippets are the texts of the search results, used when a page can't be read.
+	snippets := make(map[string]string)
+	seen := make(map[string]bool)
+
+	addURL := func(rawURL string, snippet string) {
+		normalized, err := NormalizeURL(rawURL)
+		if err != nil {
+			browserLog.Debugf("Ignoring URL %s: %v", rawURL, err)
+			return
+		}
+
+		if snippet != "" && snippets[normalized] == "" {
+			snippets[normalized] = snippet
+		}
+
+		if seen[normalized] {
+			return
+		}
+
+		seen[normalized] = true
+		urlsToOpen = append(urlsToOpen, normalized)
+	}
 
 	// Handle direct URL requests
-	if url, ok := params["url"].(string); ok {
-		urlsToOpen = append(urlsToOpen, url)
+	if url, ok := params["url"].(string); ok && url != "" {
+		addURL(url, "")
 	}
 
 	searchFor := ""
 	// Handle search requests
-	if searchQuery, ok := params["search"].(string); ok {
+	if searchQuery, ok := params["search"].(string); ok && searchQuery != "" {
 		searchFor = searchQuery
-		ddg := client.NewDuckDuckGoSearchClient()
-		searchResults, err := ddg.SearchLimited(searchQuery, MAX_RESULTS_DUCK_DUCK_GO)
+		searchResults, err := searchDuckDuckGo(c.httpClient, searchQuery, c.maxResults)
 		if err != nil {
 			return nil, err
 		}
 		for _, result := range searchResults {
-			log.Debugf("Found URL: %s", result.FormattedUrl)
-			urlsToOpen = append(urlsToOpen, fmt.Sprintf("https://%s", result.FormattedUrl))
+			browserLog.Debugf("Found URL: %s", result.URL)
+			addURL(result.URL, result.Snippet)
 		}
 	}
 
@@ -54,12 +96,17 @@ func (c *Browser) Run(params map[string]interface{}, userQuery string) (*ToolRes
 	// Visit each URL concurrently
 	for _, url := range urlsToOpen {
 		go func(url string) {
-			if url == "" {
-				resultCh <- ""
-				return
-			}
 			page, err := c.visitURL(url)
-			if err != nil {
+			if err != nil || strings.TrimSpace(page) == "" {
+				if err != nil {
+					browserLog.Debugf("Error visiting URL %s: %v", url, err)
+				}
+
+				if snippet := snippets[url]; snippet != "" {
+					resultCh <- fmt.Sprintf("URL:%s\nDATA (search result snippet, the page could not be read): %s\nNOTE: include what user has search for %s", url, snippet, searchFor)
+					return
+				}
+
 				resultCh <- ""
 				return
 			}
@@ -83,8 +130,12 @@ func (c *Browser) Run(params map[string]interface{}, userQuery string) (*ToolRes
 }
 
 func (c *Browser) visitURL(url string) (string, error) {
-	log.Debugf("Visiting URL: %s", url)
-	req, err := http.NewRequest("GET", url, nil)
+	browserLog.Debugf("Visiting URL: %s", url)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	if err != nil {
 		return "", err
@@ -98,6 +149,10 @@ func (c *Browser) visitURL(url string) (string, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
 	return articletext.GetArticleText(resp.Body)
 }
 
@@ -137,3 +192,34 @@ func (c *Browser) UseCase() []string {
 		"User requests to search information on internet.",
 	}
 }
+
+// Commands returns the commands of the browser: /search prints the search results of a query.
+func (c *Browser) Commands() []Command {
+	return []Command{
+		{
+			Name:        "search",
+			Usage:       "<query>",
+			Description: "Search the web and print the results, without asking the assistant",
+			Run: func(args string, out io.Writer) error {
+				query := strings.TrimSpace(args)
+				if query == "" {
+					return fmt.Errorf("usage: /search <query>")
+				}
+
+				results, err := searchDuckDuckGo(c.httpClient, query, c.maxResults)
+				if err != nil {
+					return err
+				}
+
+				for _, result := range results {
+					fmt.Fprintf(out, "%s\n  %s\n", result.Title, result.URL)
+					if result.Snippet != "" {
+						fmt.Fprintf(out, "  %s\n", result.Snippet)
+					}
+				}
+
+				return nil
+			},
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"io"
+	"sort"
+)
+
+// Command is a command of the interactive CLI contributed by a tool, run with /name.
+type Command struct {
+	// Name is the name of the command, without the slash.
+	Name string
+	// Usage describes the arguments of the command.
+	Usage string
+	// Description describes what the command does.
+	Description string
+	// Run runs the command with the rest of the input line, writing its output to out.
+	Run func(args string, out io.Writer) error
+}
+
+// CommandProvider is implemented by the tools that contribute commands to the interactive CLI.
+type CommandProvider interface {
+	// Commands returns the commands of the tool.
+	Commands() []Command
+}
+
+// Commands returns the commands contributed by the tools of the repository, sorted by name.
+func (r *ToolRepository) Commands() []Command {
+	commands := make([]Command, 0)
+
+	for _, tool := range r.Tools {
+		if provider, ok := tool.(CommandProvider); ok {
+			commands = append(commands, provider.Commands()...)
+		}
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+
+	return commands
+}
@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxRedirects is the default maximum number of redirects followed.
+	DefaultMaxRedirects = 5
+	// DefaultMaxBodySize is the default maximum size of a response body (5 MiB).
+	DefaultMaxBodySize = 5 << 20
+	// DefaultConnectTimeout is the default timeout to connect to a host.
+	DefaultConnectTimeout = 5 * time.Second
+	// DefaultReadTimeout is the default timeout to read the whole response.
+	DefaultReadTimeout = 20 * time.Second
+)
+
+var (
+	// ErrBlockedAddress is returned when a host resolves to an address that tools are not allowed to reach.
+	ErrBlockedAddress = errors.New("address is not allowed")
+	// ErrBlockedScheme is returned when a URL uses a scheme other than http or https.
+	ErrBlockedScheme = errors.New("scheme is not allowed")
+	// ErrTooManyRedirects is returned when a request is redirected more than the allowed number of times.
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrBodyTooLarge is returned when a response body is larger than the allowed size.
+	ErrBodyTooLarge = errors.New("response body too large")
+)
+
+// blockedNetworks are the ranges that aren't covered by the net.IP helpers but must not be reached either.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "This" network
+	"100.64.0.0/10", // Carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // Benchmarking
+	"240.0.0.0/4",   // Reserved
+	"64:ff9b::/96",  // NAT64
+)
+
+// NewHTTPClient creates the HTTP client used by the tools. It only talks http and https, resolves every
+// host before connecting and refuses private, loopback and link-local addresses unless they are allowed
+// in the configuration. Redirects and response bodies are capped.
+func NewHTTPClient(config config.HTTP) *http.Client {
+	guard := newAddressGuard(config)
+
+	connectTimeout := config.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+
+	readTimeout := config.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = DefaultReadTimeout
+	}
+
+	maxRedirects := config.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	maxBodySize := config.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	dialer := &net.Dialer{
+		Timeout: connectTimeout,
+	}
+
+	transport := &http.Transport{
+		// A proxy would connect on our behalf and bypass the address checks.
+		Proxy:                 nil,
+		DialContext:           guard.dialContext(dialer),
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: &limitedTransport{
+			transport:   transport,
+			maxBodySize: maxBodySize,
+		},
+		Timeout: readTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return ErrTooManyRedirects
+			}
+
+			log.Debugf("Following redirect to: %s", req.URL)
+			return nil
+		},
+	}
+}
+
+// limitedTransport checks the scheme of every request, including each redirect hop, and caps the size of the response bodies.
+type limitedTransport struct {
+	transport   http.RoundTripper
+	maxBodySize int64
+}
+
+// RoundTrip executes a single HTTP transaction.
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrBlockedScheme, req.URL.Scheme)
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ContentLength > t.maxBodySize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
+	}
+
+	resp.Body = &limitedBody{
+		body:      resp.Body,
+		remaining: t.maxBodySize,
+	}
+
+	return resp, nil
+}
+
+// limitedBody fails once more than the allowed number of bytes has been read.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+// Read reads from the body.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+
+	// Read one more byte than allowed to detect bodies over the limit.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+
+	if b.remaining < 0 {
+		return n - int(-b.remaining), ErrBodyTooLarge
+	}
+
+	return n, err
+}
+
+// Close closes the body.
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// addressGuard decides which addresses the tools can connect to.
+type addressGuard struct {
+	allowedHosts    map[string]bool
+	allowedNetworks []*net.IPNet
+	resolver        *net.Resolver
+}
+
+// newAddressGuard creates a new addressGuard.
+func newAddressGuard(config config.HTTP) *addressGuard {
+	guard := &addressGuard{
+		allowedHosts:    make(map[string]bool),
+		allowedNetworks: make([]*net.IPNet, 0),
+		resolver:        net.DefaultResolver,
+	}
+
+	for _, host := range config.AllowedHosts {
+		guard.allowedHosts[strings.ToLower(host)] = true
+	}
+
+	for _, cidr := range config.AllowedNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Warnf("Ignoring invalid allowed network %q: %v", cidr, err)
+			continue
+		}
+		guard.allowedNetworks = append(guard.allowedNetworks, network)
+	}
+
+	return guard
+}
+
+// dialContext resolves the host and connects to the first allowed address. The connection is made to
+// the resolved IP, so the host can't resolve to a different address between the check and the dial.
+func (g *addressGuard) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := g.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		hostAllowed := g.allowedHosts[strings.ToLower(host)]
+		var lastErr error = fmt.Errorf("%w: %s does not resolve to any address", ErrBlockedAddress, host)
+
+		for _, ip := range ips {
+			if !hostAllowed && !g.isAllowed(ip.IP) {
+				log.Warnf("Blocked connection to %s (%s)", host, ip.IP)
+				lastErr = fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, ip.IP)
+				continue
+			}
+
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			return conn, nil
+		}
+
+		return nil, lastErr
+	}
+}
+
+// isAllowed checks if the IP is public or in one of the allowed networks.
+func (g *addressGuard) isAllowed(ip net.IP) bool {
+	for _, network := range g.allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return !isBlockedIP(ip)
+}
+
+// isBlockedIP checks if the IP is private, loopback, link-local or otherwise not publicly routable.
+func isBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mustParseCIDRs parses a list of CIDR ranges, panicking if one of them is invalid.
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+		{"::ffff:8.8.8.8", false},
+
+		// Private
+		{"10.0.0.1", true},
+		{"172.16.5.4", true},
+		{"192.168.1.1", true},
+		{"fd00::1", true},
+
+		// Loopback
+		{"127.0.0.1", true},
+		{"127.255.255.254", true},
+		{"::1", true},
+
+		// Link-local, like the metadata services of the clouds
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+
+		// IPv4-mapped IPv6
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"::ffff:192.168.0.1", true},
+
+		// Unspecified, multicast and the other reserved ranges
+		{"0.0.0.0", true},
+		{"::", true},
+		{"224.0.0.1", true},
+		{"ff02::1", true},
+		{"100.64.0.1", true},
+		{"198.18.0.1", true},
+		{"240.0.0.1", true},
+		{"64:ff9b::7f00:1", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			ip := net.ParseIP(test.ip)
+			if ip == nil {
+				t.Fatalf("invalid IP %q", test.ip)
+			}
+
+			if got := isBlockedIP(ip); got != test.blocked {
+				t.Errorf("isBlockedIP(%s) = %v, want %v", test.ip, got, test.blocked)
+			}
+		})
+	}
+}
+
+func TestAddressGuardAllowedNetworks(t *testing.T) {
+	guard := newAddressGuard(config.HTTP{AllowedNetworks: []string{"10.1.0.0/16", "invalid"}})
+
+	if !guard.isAllowed(net.ParseIP("10.1.2.3")) {
+		t.Error("10.1.2.3 is in an allowed network and must be allowed")
+	}
+
+	if guard.isAllowed(net.ParseIP("10.2.0.1")) {
+		t.Error("10.2.0.1 is not in an allowed network and must be blocked")
+	}
+
+	if !guard.isAllowed(net.ParseIP("8.8.8.8")) {
+		t.Error("8.8.8.8 is public and must be allowed")
+	}
+}
+
+func TestHTTPClientBlocksLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret")
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClient(config.HTTP{}).Get(server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("got error %v, want %v", err, ErrBlockedAddress)
+	}
+}
+
+func TestHTTPClientAllowedNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	resp, err := NewHTTPClient(config.HTTP{AllowedNetworks: []string{"127.0.0.0/8"}}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("got body %q, want %q", body, "ok")
+	}
+}
+
+func TestHTTPClientBlocksRedirectToPrivate(t *testing.T) {
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret")
+	}))
+	defer private.Close()
+
+	// The first server is reached through an allowed host name, the redirect targets the IP directly.
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, private.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	publicURL := strings.Replace(public.URL, "127.0.0.1", "localhost", 1)
+
+	_, err := NewHTTPClient(config.HTTP{AllowedHosts: []string{"localhost"}}).Get(publicURL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("got error %v, want %v", err, ErrBlockedAddress)
+	}
+}
+
+func TestHTTPClientBlocksScheme(t *testing.T) {
+	for _, rawURL := range []string{"file:///etc/passwd", "ftp://example.com/", "gopher://example.com/"} {
+		_, err := NewHTTPClient(config.HTTP{}).Get(rawURL)
+		if !errors.Is(err, ErrBlockedScheme) {
+			t.Errorf("%s: got error %v, want %v", rawURL, err, ErrBlockedScheme)
+		}
+	}
+}
+
+func TestHTTPClientMaxRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, server.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(config.HTTP{AllowedNetworks: []string{"127.0.0.0/8"}, MaxRedirects: 2})
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("got error %v, want %v", err, ErrTooManyRedirects)
+	}
+}
+
+func TestHTTPClientMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name   string
+		length bool
+	}{
+		{"with content length", true},
+		{"chunked", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := strings.Repeat("a", 100)
+				if test.length {
+					w.Header().Set("Content-Length", "100")
+				} else {
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, body)
+			}))
+			defer server.Close()
+
+			client := NewHTTPClient(config.HTTP{AllowedNetworks: []string{"127.0.0.0/8"}, MaxBodySize: 10})
+
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				defer resp.Body.Close()
+				_, err = io.ReadAll(resp.Body)
+			}
+
+			if !errors.Is(err, ErrBodyTooLarge) {
+				t.Fatalf("got error %v, want %v", err, ErrBodyTooLarge)
+			}
+		})
+	}
+}
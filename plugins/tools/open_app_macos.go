@@ -9,6 +9,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// openAppMacOSLog is the logger of the open_app_macos tool.
+var openAppMacOSLog = log.WithField("tool", "open_app_macos")
+
 type OpenAppMacOS struct {
 }
 
@@ -17,7 +20,7 @@ func NewOpenAppMacOS(config *config.Base) *OpenAppMacOS {
 }
 
 func (c *OpenAppMacOS) Run(params map[string]interface{}, userQuery string) (*ToolResponse, error) {
-	log.Debugf("Running the OpenAppMacOS tool with the following parameters: %v", params)
+	openAppMacOSLog.Debugf("Running the OpenAppMacOS tool with the following parameters: %v", params)
 
 	app := params["app"].(string)
 	arguments := ""
@@ -49,7 +52,7 @@ func (c *OpenAppMacOS) Description() string {
 	entries, err := os.ReadDir("/Applications")
 
 	if err != nil {
-		log.Errorf("Error while reading the /Applications folder: %v", err)
+		openAppMacOSLog.Errorf("Error while reading the /Applications folder: %v", err)
 	}
 
 	for _, entry := range entries {
@@ -60,7 +63,7 @@ func (c *OpenAppMacOS) Description() string {
 	installedApplicationsJSON, err := json.Marshal(installedApplications)
 
 	if err != nil {
-		log.Errorf("Error while converting the installed applications to JSON: %v", err)
+		openAppMacOSLog.Errorf("Error while converting the installed applications to JSON: %v", err)
 	}
 
 	return "OpenAppMacOS is a tool that allows you to open an application on macOS. The installed applications are: " + string(installedApplicationsJSON)
@@ -3,15 +3,59 @@ package tools
 import (
 	"encoding/json"
 	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/Pishia-IA/core/config"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
 	// repository is a repository that contains all the tools.
 	repository *ToolRepository
+	// mu protects repository, it is replaced when the configuration is reloaded.
+	mu sync.RWMutex
+	// registrations are the built-in tools.
+	registrations = map[string]registration{
+		"browser": {
+			constructor:      func(config *config.Base) Tools { return NewBrowser(config) },
+			toggle:           func(tool *config.Tool) config.Toggle { return tool.Browser.Toggle },
+			enabledByDefault: true,
+			available:        always,
+		},
+		// The reservation tool doesn't place calls yet, it is disabled unless explicitly enabled.
+		"reservation": {
+			constructor:      func(config *config.Base) Tools { return NewReservation(config) },
+			toggle:           func(tool *config.Tool) config.Toggle { return tool.Reservation.Toggle },
+			enabledByDefault: false,
+			available:        always,
+		},
+		"open_app_macos": {
+			constructor:      func(config *config.Base) Tools { return NewOpenAppMacOS(config) },
+			toggle:           func(tool *config.Tool) config.Toggle { return tool.OpenAppMacOS.Toggle },
+			enabledByDefault: true,
+			available:        func() bool { return runtime.GOOS == "darwin" },
+		},
+	}
 )
 
+// registration describes a built-in tool.
+type registration struct {
+	// constructor creates the tool.
+	constructor func(*config.Base) Tools
+	// toggle returns the toggle of the tool in the configuration.
+	toggle func(*config.Tool) config.Toggle
+	// enabledByDefault is used when the configuration doesn't enable or disable the tool.
+	enabledByDefault bool
+	// available checks if the tool can run on this system.
+	available func() bool
+}
+
+// always is used for the tools available on every system.
+func always() bool {
+	return true
+}
+
 type ToolParameter struct {
 	Type        string `json:"type"`
 	Format      string `json:"format,omitempty"`
@@ -56,33 +100,8 @@ func NewToolRepository() *ToolRepository {
 func (r *ToolRepository) DumpToolsJSON() (string, error) {
 	var tools []map[string]interface{}
 
-	for name, tool := range r.Tools {
-		toolMap := map[string]interface{}{
-			"type": "function",
-			"function": map[string]interface{}{
-				"name":        name,
-				"description": tool.Description(),
-				"parameters": map[string]interface{}{
-					"type":       "object",
-					"properties": make(map[string]map[string]string),
-					"required":   []string{},
-				},
-				"use_case": tool.UseCase(),
-			},
-		}
-
-		params := tool.Parameters()
-		propMap := toolMap["function"].(map[string]interface{})["parameters"].(map[string]interface{})["properties"].(map[string]map[string]string)
-		var reqParams []string
-
-		for paramName, param := range params {
-			propMap[paramName] = map[string]string{"type": param.Type}
-			if param.Required {
-				reqParams = append(reqParams, paramName)
-			}
-		}
-
-		toolMap["function"].(map[string]interface{})["parameters"].(map[string]interface{})["required"] = reqParams
+	for name := range r.Tools {
+		toolMap, _ := r.Schema(name)
 		tools = append(tools, toolMap)
 	}
 
@@ -94,6 +113,42 @@ func (r *ToolRepository) DumpToolsJSON() (string, error) {
 	return string(b), nil
 }
 
+// Schema returns the schema of a tool, as dumped by DumpToolsJSON.
+func (r *ToolRepository) Schema(name string) (map[string]interface{}, bool) {
+	tool, ok := r.Tools[name]
+	if !ok {
+		return nil, false
+	}
+
+	toolMap := map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        name,
+			"description": tool.Description(),
+			"parameters": map[string]interface{}{
+				"type":       "object",
+				"properties": make(map[string]map[string]string),
+				"required":   []string{},
+			},
+			"use_case": tool.UseCase(),
+		},
+	}
+
+	params := tool.Parameters()
+	propMap := toolMap["function"].(map[string]interface{})["parameters"].(map[string]interface{})["properties"].(map[string]map[string]string)
+	var reqParams []string
+
+	for paramName, param := range params {
+		propMap[paramName] = map[string]string{"type": param.Type}
+		if param.Required {
+			reqParams = append(reqParams, paramName)
+		}
+	}
+
+	toolMap["function"].(map[string]interface{})["parameters"].(map[string]interface{})["required"] = reqParams
+	return toolMap, true
+}
+
 // Register registers a tool in the repository.
 func (r *ToolRepository) Register(name string, tool Tools) {
 	r.Tools[name] = tool
@@ -107,19 +162,97 @@ func (r *ToolRepository) Get(name string) (Tools, bool) {
 
 // GetRepository gets the repository.
 func GetRepository() *ToolRepository {
+	mu.RLock()
+	defer mu.RUnlock()
 	return repository
 }
 
-// StartTools starts the tools.
-func StartTools(config *config.Base) {
-	repository = NewToolRepository()
+// SetRepository replaces the repository.
+func SetRepository(r *ToolRepository) {
+	mu.Lock()
+	defer mu.Unlock()
+	repository = r
+}
+
+// Names returns the names of the tools available on this system, sorted.
+func Names() []string {
+	names := make([]string, 0, len(registrations))
+
+	for name, registration := range registrations {
+		if registration.available() {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// RegisteredNames returns the names of all the tools, even the ones not available on this system,
+// sorted. A configuration shared between systems can list the tools of all of them.
+func RegisteredNames() []string {
+	names := make([]string, 0, len(registrations))
 
-	repository.Register("browser", NewBrowser(config))
-	repository.Register("reservation", NewReservation(config))
+	for name := range registrations {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
 
-	switch runtime.GOOS {
-	case "darwin":
-		repository.Register("open_app_macos", NewOpenAppMacOS(config))
+// IsEnabled checks if the tool is registered with the configuration: it must be available on this
+// system, enabled, and part of the tool set if there is one.
+func IsEnabled(config *config.Base, name string) bool {
+	registration, ok := registrations[name]
+	if !ok || !registration.available() {
+		return false
 	}
 
+	if !registration.toggle(&config.Tool).IsEnabled(registration.enabledByDefault) {
+		return false
+	}
+
+	if config.Tool.Set == "" {
+		return true
+	}
+
+	for _, toolName := range config.Tool.Sets[config.Tool.Set] {
+		if toolName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewTool creates a tool available on this system, enabled or not.
+func NewTool(config *config.Base, name string) (Tools, bool) {
+	registration, ok := registrations[name]
+	if !ok || !registration.available() {
+		return nil, false
+	}
+
+	return registration.constructor(config), true
+}
+
+// NewToolRepositoryFromConfig creates a repository with the tools enabled in the configuration.
+func NewToolRepositoryFromConfig(config *config.Base) *ToolRepository {
+	r := NewToolRepository()
+
+	for _, name := range Names() {
+		if !IsEnabled(config, name) {
+			log.Debugf("Tool %s is disabled", name)
+			continue
+		}
+
+		r.Register(name, registrations[name].constructor(config))
+	}
+
+	return r
+}
+
+// StartTools starts the tools.
+func StartTools(config *config.Base) {
+	SetRepository(NewToolRepositoryFromConfig(config))
 }
@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+)
+
+func TestToolSetWithUnavailableTool(t *testing.T) {
+	base := &config.Base{}
+	base.Tool.Set = "desktop"
+	base.Tool.Sets = map[string][]string{"desktop": {"browser", "open_app_macos"}}
+
+	err := config.Validate(base, config.Names{Tools: RegisteredNames()})
+
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, problem := range validationErr.Problems {
+			if strings.HasPrefix(problem.Path, "tool.") {
+				t.Errorf("unexpected problem %s: %s", problem.Path, problem.Message)
+			}
+		}
+	}
+
+	if got, want := IsEnabled(base, "open_app_macos"), runtime.GOOS == "darwin"; got != want {
+		t.Errorf("IsEnabled(open_app_macos) = %v, want %v on %s", got, want, runtime.GOOS)
+	}
+
+	if got, want := slices.Contains(Names(), "open_app_macos"), runtime.GOOS == "darwin"; got != want {
+		t.Errorf("Names() contains open_app_macos = %v, want %v on %s", got, want, runtime.GOOS)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// reservationLog is the logger of the reservation tool.
+var reservationLog = log.WithField("tool", "reservation")
+
 type Reservation struct {
 }
 
@@ -13,7 +16,7 @@ func NewReservation(config *config.Base) *Reservation {
 }
 
 func (c *Reservation) Run(params map[string]interface{}, userQuery string) (*ToolResponse, error) {
-	log.Debugf("Running the Reservation tool with the following parameters: %v", params)
+	reservationLog.Debugf("Running the Reservation tool with the following parameters: %v", params)
 
 	return &ToolResponse{
 		Success: true,
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// duckDuckGoURL is the URL of the HTML version of DuckDuckGo.
+const duckDuckGoURL = "https://html.duckduckgo.com/html/"
+
+// trackingParameters are the query parameters removed from the URLs, they don't change the page content.
+var trackingParameters = map[string]bool{
+	"fbclid":   true,
+	"gclid":    true,
+	"dclid":    true,
+	"gbraid":   true,
+	"wbraid":   true,
+	"msclkid":  true,
+	"yclid":    true,
+	"igshid":   true,
+	"mc_cid":   true,
+	"mc_eid":   true,
+	"_ga":      true,
+	"_gl":      true,
+	"ref_src":  true,
+	"ref_url":  true,
+	"spm":      true,
+	"srsltid":  true,
+	"mkt_tok":  true,
+	"oly_anon": true,
+	"oly_enc":  true,
+}
+
+// redirectWrappers maps the hosts that wrap links in a redirect to the query parameter that holds the real URL.
+var redirectWrappers = map[string][]string{
+	"duckduckgo.com":      {"uddg"},
+	"html.duckduckgo.com": {"uddg"},
+	"www.google.com":      {"q", "url"},
+	"google.com":          {"q", "url"},
+	"l.facebook.com":      {"u"},
+	"out.reddit.com":      {"url"},
+}
+
+// SearchResult is a result of a web search.
+type SearchResult struct {
+	// Title is the title of the page.
+	Title string
+	// URL is the normalized URL of the page.
+	URL string
+	// Snippet is the text shown by the search engine for the page.
+	Snippet string
+}
+
+// searchDuckDuckGo searches the query on DuckDuckGo and returns at most limit results, ads are skipped.
+func searchDuckDuckGo(client *http.Client, query string, limit int) ([]SearchResult, error) {
+	req, err := http.NewRequest("GET", duckDuckGoURL+"?q="+url.QueryEscape(query), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "Pishia-IA")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0)
+
+	doc.Find(".results .result").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if s.HasClass("result--ad") {
+			return true
+		}
+
+		link := s.Find(".result__a").First()
+		href, ok := link.Attr("href")
+		if !ok {
+			return true
+		}
+
+		normalized, err := NormalizeURL(href)
+		if err != nil {
+			return true
+		}
+
+		results = append(results, SearchResult{
+			Title:   strings.TrimSpace(link.Text()),
+			URL:     normalized,
+			Snippet: strings.TrimSpace(s.Find(".result__snippet").Text()),
+		})
+
+		return limit <= 0 || len(results) < limit
+	})
+
+	return results, nil
+}
+
+// NormalizeURL resolves redirect wrappers, strips tracking parameters and fragments, and puts the
+// scheme, host and port in a canonical form so that the same page is represented by the same string.
+// The rest of the query is kept as it is.
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	} else if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	// Follow nested wrappers, but not forever.
+	for i := 0; i < 3; i++ {
+		target, ok := unwrapRedirect(u)
+		if !ok {
+			break
+		}
+		u = target
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: %s", ErrBlockedScheme, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("URL without host: %s", rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	u.RawQuery = stripTrackingParameters(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// stripTrackingParameters removes the tracking parameters from a raw query. The other parameters are
+// kept as they are and in the same order, signed URLs and the ones with repeated keys still work.
+func stripTrackingParameters(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	parameters := strings.Split(rawQuery, "&")
+	kept := make([]string, 0, len(parameters))
+
+	for _, parameter := range parameters {
+		name, _, _ := strings.Cut(parameter, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		name = strings.ToLower(name)
+		if trackingParameters[name] || strings.HasPrefix(name, "utm_") {
+			continue
+		}
+
+		kept = append(kept, parameter)
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// unwrapRedirect returns the target of a redirect wrapper, like the links of the search engines.
+func unwrapRedirect(u *url.URL) (*url.URL, bool) {
+	parameters, ok := redirectWrappers[strings.ToLower(u.Hostname())]
+	if !ok {
+		return nil, false
+	}
+
+	for _, parameter := range parameters {
+		target := u.Query().Get(parameter)
+		if target == "" {
+			continue
+		}
+
+		targetURL, err := url.Parse(target)
+		if err != nil || targetURL.Host == "" {
+			continue
+		}
+
+		return targetURL, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,479 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+)
+
+const (
+	// finishReasonStop is the finish reason of a complete answer.
+	finishReasonStop = "stop"
+	// streamDone is the data of the last event of a streamed completion.
+	streamDone = "[DONE]"
+)
+
+// chatCompletionRequest is the body of a request to /v1/chat/completions. The fields not listed are
+// accepted and ignored.
+type chatCompletionRequest struct {
+	// Model is the model to answer with, the configured one if it is empty.
+	Model string `json:"model"`
+	// Messages are the messages of the conversation, the last one is the message to answer.
+	Messages []chatMessage `json:"messages"`
+	// Stream streams the answer as Server-Sent Events.
+	Stream bool `json:"stream"`
+	// StreamOptions are the options of the stream.
+	StreamOptions *chatStreamOptions `json:"stream_options,omitempty"`
+	// Temperature is the randomness of the answer, from 0 to 2.
+	Temperature *float64 `json:"temperature"`
+	// TopP keeps the most likely tokens up to this cumulative probability, from 0 to 1.
+	TopP *float64 `json:"top_p"`
+	// MaxTokens is the maximum number of tokens of the answer.
+	MaxTokens *int `json:"max_tokens"`
+	// MaxCompletionTokens replaces MaxTokens in the recent clients.
+	MaxCompletionTokens *int `json:"max_completion_tokens"`
+	// Seed makes the answer reproducible for the same conversation.
+	Seed *int `json:"seed"`
+	// Stop are the sequences that end the answer.
+	Stop chatStop `json:"stop"`
+	// ResponseFormat is the format of the answer.
+	ResponseFormat *chatResponseFormat `json:"response_format"`
+}
+
+// options returns the parameters of the generation of the request.
+func (r *chatCompletionRequest) options() *generationOptions {
+	options := &generationOptions{
+		Temperature: r.Temperature,
+		TopP:        r.TopP,
+		MaxTokens:   r.MaxTokens,
+		Seed:        r.Seed,
+		Stop:        r.Stop,
+	}
+
+	if r.MaxCompletionTokens != nil {
+		options.MaxTokens = r.MaxCompletionTokens
+	}
+
+	if r.ResponseFormat != nil {
+		format := "text"
+		if r.ResponseFormat.Type == "json_object" {
+			format = "json"
+		}
+		options.Format = &format
+	}
+
+	return options
+}
+
+// chatStop are the stop sequences of a completion, either a string or a list of strings.
+type chatStop []string
+
+// UnmarshalJSON decodes the stop sequences from a string, null or a list of strings.
+func (s *chatStop) UnmarshalJSON(data []byte) error {
+	var stop *string
+	if err := json.Unmarshal(data, &stop); err == nil {
+		if stop != nil {
+			*s = chatStop{*stop}
+		}
+		return nil
+	}
+
+	var stops []string
+	if err := json.Unmarshal(data, &stops); err != nil {
+		return fmt.Errorf("stop must be a string or a list of strings")
+	}
+
+	*s = stops
+	return nil
+}
+
+// chatResponseFormat is the format of the answer of a completion.
+type chatResponseFormat struct {
+	// Type is text, or json_object to answer with a JSON object.
+	Type string `json:"type"`
+}
+
+// chatStreamOptions are the options of a streamed completion.
+type chatStreamOptions struct {
+	// IncludeUsage sends the number of tokens used in a last chunk.
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatMessage is a message of a conversation, as sent by the OpenAI clients.
+type chatMessage struct {
+	// Role is the role of the author of the message: system, user, assistant or tool.
+	Role string `json:"role"`
+	// Content is the text of the message.
+	Content chatContent `json:"content"`
+	// ToolCalls are the tools called by an assistant message, they can't be replayed.
+	ToolCalls json.RawMessage `json:"tool_calls"`
+}
+
+// chatContent is the content of a message, either a string or a list of parts of which only the text
+// parts are kept.
+type chatContent string
+
+// UnmarshalJSON decodes the content from a string, null or a list of parts.
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	var text *string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text != nil {
+			*c = chatContent(*text)
+		}
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or a list of parts")
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	*c = chatContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// chatCompletion is a completion, or a chunk of a streamed one.
+type chatCompletion struct {
+	// ID is the ID of the completion, the same for all the chunks.
+	ID string `json:"id"`
+	// Object is chat.completion or chat.completion.chunk.
+	Object string `json:"object"`
+	// Created is when the completion was created, in seconds since the epoch.
+	Created int64 `json:"created"`
+	// Model is the model that answered.
+	Model string `json:"model"`
+	// Choices are the answers, Pishia always gives one.
+	Choices []chatChoice `json:"choices"`
+	// Usage is the number of tokens used, when the backend reports it.
+	Usage *assistants.Usage `json:"usage,omitempty"`
+}
+
+// chatChoice is an answer of a completion.
+type chatChoice struct {
+	// Index is the index of the answer.
+	Index int `json:"index"`
+	// Message is the answer of a completion.
+	Message *chatAnswer `json:"message,omitempty"`
+	// Delta is the part of the answer of a chunk.
+	Delta *chatAnswer `json:"delta,omitempty"`
+	// FinishReason is why the answer ended, null until the last chunk.
+	FinishReason *string `json:"finish_reason"`
+}
+
+// chatAnswer is the message, or a part of it, answered by the assistant.
+type chatAnswer struct {
+	// Role is the role of the author, only set in the first chunk.
+	Role string `json:"role,omitempty"`
+	// Content is the text of the answer.
+	Content string `json:"content"`
+}
+
+// openAIModel is a model of the /v1/models list.
+type openAIModel struct {
+	// ID is the name of the model.
+	ID string `json:"id"`
+	// Object is always model.
+	Object string `json:"object"`
+	// Created is when the model was created, in seconds since the epoch.
+	Created int64 `json:"created"`
+	// OwnedBy is the owner of the model.
+	OwnedBy string `json:"owned_by"`
+}
+
+// handleChatCompletions answers a conversation like the OpenAI API, running the prompt and the tools of
+// Pishia. Every request gets its own assistant, the conversation is given by the client.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: %v", err)
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+		return
+	}
+
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "user" || strings.TrimSpace(string(last.Content)) == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "the last message must be a user message with a content")
+		return
+	}
+
+	messages, err := conversation(req.Messages[:len(req.Messages)-1])
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "%v", err)
+		return
+	}
+
+	assistant, cfg, status, err := newAssistant(r.Context(), createSessionRequest{Model: req.Model, Options: req.options()})
+	if err != nil {
+		errorType := "server_error"
+		if status < http.StatusInternalServerError {
+			errorType = "invalid_request_error"
+		}
+
+		writeOpenAIError(w, status, errorType, "%v", err)
+		return
+	}
+
+	assistant.SetHistory(append(assistant.History(), messages...))
+
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + core.NewSessionID(),
+		Created: time.Now().Unix(),
+		Model:   modelOf(cfg),
+	}
+
+	var usage *assistants.Usage
+	assistant.SetEventHandler(func(event assistants.Event) {
+		switch event.Type {
+		case assistants.EventUsage:
+			usage = addUsage(usage, event.Usage)
+		case assistants.EventToolCallStarted:
+			serverLog.WithField("completion", completion.ID).Debugf("Calling the tool %s", event.Tool)
+		}
+	})
+
+	var stream *eventStream
+	if req.Stream {
+		stream = newEventStream(w)
+		stream.SendJSON("", completion.chunk(&chatAnswer{Role: "assistant"}, nil))
+	}
+
+	var answer strings.Builder
+	var answerErr error
+
+	err = assistant.SendRequest(r.Context(), string(last.Content), func(output string, err error) {
+		if err != nil {
+			answerErr = errors.Join(answerErr, err)
+			return
+		}
+
+		answer.WriteString(output)
+		if stream != nil {
+			stream.SendJSON("", completion.chunk(&chatAnswer{Content: output}, nil))
+		}
+	})
+	if err == nil {
+		err = answerErr
+	}
+
+	if err != nil {
+		serverLog.WithField("completion", completion.ID).Warnf("Answering the completion: %v", err)
+
+		if stream != nil {
+			stream.SendJSON("", openAIError("server_error", err.Error()))
+			stream.write("", []byte(streamDone))
+			return
+		}
+
+		writeOpenAIError(w, http.StatusBadGateway, "server_error", "%v", err)
+		return
+	}
+
+	stop := finishReasonStop
+
+	if stream != nil {
+		stream.SendJSON("", completion.chunk(&chatAnswer{}, &stop))
+
+		if req.StreamOptions != nil && req.StreamOptions.IncludeUsage && usage != nil {
+			chunk := completion.chunk(nil, nil)
+			chunk.Choices, chunk.Usage = []chatChoice{}, usage
+			stream.SendJSON("", chunk)
+		}
+
+		stream.write("", []byte(streamDone))
+		return
+	}
+
+	completion.Object = "chat.completion"
+	completion.Usage = usage
+	completion.Choices = []chatChoice{{
+		Message:      &chatAnswer{Role: "assistant", Content: strings.TrimSpace(answer.String())},
+		FinishReason: &stop,
+	}}
+
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// chunk returns a chunk of the streamed completion with the delta of the answer.
+func (c chatCompletion) chunk(delta *chatAnswer, finishReason *string) chatCompletion {
+	c.Object = "chat.completion.chunk"
+	c.Choices = []chatChoice{{Delta: delta, FinishReason: finishReason}}
+	return c
+}
+
+// conversation returns the messages of the client as the history of an assistant, appended after the
+// system prompt of Pishia which describes the tools. The tool calls of the client can't be replayed, the
+// tool messages and the assistant messages with tool calls are rejected.
+func conversation(messages []chatMessage) ([]assistants.Message, error) {
+	history := make([]assistants.Message, 0, len(messages))
+
+	for i, message := range messages {
+		switch message.Role {
+		case "system", "developer":
+			history = append(history, assistants.Message{Role: "system", Content: string(message.Content)})
+		case "user", "assistant":
+			if len(message.ToolCalls) > 0 && string(message.ToolCalls) != "null" && string(message.ToolCalls) != "[]" {
+				return nil, fmt.Errorf("messages[%d]: the tool calls are not supported, Pishia calls its own tools", i)
+			}
+
+			history = append(history, assistants.Message{Role: message.Role, Content: string(message.Content)})
+		case "tool", "function":
+			return nil, fmt.Errorf("messages[%d]: the %s messages are not supported, Pishia calls its own tools", i, message.Role)
+		default:
+			return nil, fmt.Errorf("messages[%d]: unknown role %q", i, message.Role)
+		}
+	}
+
+	return history, nil
+}
+
+// addUsage adds the tokens of usage to total, a request can call the backend several times.
+func addUsage(total *assistants.Usage, usage *assistants.Usage) *assistants.Usage {
+	if usage == nil {
+		return total
+	}
+
+	if total == nil {
+		total = &assistants.Usage{}
+	}
+
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	return total
+}
+
+// handleListModels lists the models like the OpenAI API.
+func (s *Server) handleListModels(w http.ResponseWriter, r *http.Request) {
+	cfg := core.Config()
+	if cfg == nil {
+		writeOpenAIError(w, http.StatusServiceUnavailable, "server_error", "no configuration loaded")
+		return
+	}
+
+	models, err := listModels(r.Context(), cfg)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadGateway, "server_error", "listing the models: %v", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+// checkModel checks that the model of the configuration can be used: the one the core runs with or one
+// installed in the backend. The HTTP status of the error is returned with it.
+func checkModel(ctx context.Context, cfg *config.Base) (int, error) {
+	model := modelOf(cfg)
+
+	current := core.Config()
+	if current != nil && cfg.Assistants.Plugin == current.Assistants.Plugin && model == modelOf(current) {
+		return http.StatusOK, nil
+	}
+
+	models, err := backendModels(ctx, cfg)
+	if err != nil {
+		return http.StatusBadGateway, fmt.Errorf("listing the models: %w", err)
+	}
+
+	for _, available := range models {
+		if available.ID == model {
+			return http.StatusOK, nil
+		}
+	}
+
+	return http.StatusNotFound, fmt.Errorf("the model %q does not exist", model)
+}
+
+// listModels returns the models of the backend of the configured plugin, the configured model first.
+func listModels(ctx context.Context, cfg *config.Base) ([]openAIModel, error) {
+	configured := modelOf(cfg)
+	models := []openAIModel{{ID: configured, Object: "model", OwnedBy: cfg.Assistants.Plugin}}
+
+	available, err := backendModels(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range available {
+		if model.ID == configured {
+			models[0].Created = model.Created
+			continue
+		}
+
+		models = append(models, model)
+	}
+
+	return models, nil
+}
+
+// backendModels returns the models installed in the backend of the plugin of the configuration.
+func backendModels(ctx context.Context, cfg *config.Base) ([]openAIModel, error) {
+	models := make([]openAIModel, 0)
+
+	switch cfg.Assistants.Plugin {
+	case "ollama":
+		list, err := assistants.NewOllamaClient(cfg).ListModels(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, model := range list.Models {
+			models = append(models, openAIModel{ID: model.Name, Object: "model", Created: model.ModifiedAt.Unix(), OwnedBy: "ollama"})
+		}
+	case "openai":
+		client, err := assistants.NewOpenAIClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		list, err := client.ListModels(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, model := range list.Models {
+			models = append(models, openAIModel{ID: model.ID, Object: "model", Created: model.CreatedAt, OwnedBy: model.OwnedBy})
+		}
+	}
+
+	return models, nil
+}
+
+// openAIError returns an error in the format of the OpenAI API.
+func openAIError(errorType string, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"code":    nil,
+		},
+	}
+}
+
+// writeOpenAIError writes the error in the format of the OpenAI API.
+func writeOpenAIError(w http.ResponseWriter, status int, errorType string, format string, args ...interface{}) {
+	writeJSON(w, status, openAIError(errorType, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Pishia-IA/core/plugins/assistants"
+)
+
+func TestConversation(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages string
+		valid    bool
+	}{
+		{"text messages", `[{"role": "system", "content": "be brief"}, {"role": "user", "content": "hi"}, {"role": "assistant", "content": "hello"}]`, true},
+		{"empty tool calls", `[{"role": "assistant", "content": "hello", "tool_calls": []}]`, true},
+		{"tool message", `[{"role": "tool", "content": "42", "tool_call_id": "call_1"}]`, false},
+		{"assistant tool calls", `[{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function"}]}]`, false},
+		{"unknown role", `[{"role": "robot", "content": "hi"}]`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var messages []chatMessage
+
+			err := json.Unmarshal([]byte(test.messages), &messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			history, err := conversation(messages)
+			if test.valid && err != nil {
+				t.Fatalf("got error %v, want none", err)
+			}
+
+			if !test.valid && err == nil {
+				t.Fatalf("got history %v, want an error", history)
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	var total *assistants.Usage
+
+	total = addUsage(total, &assistants.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12})
+	total = addUsage(total, nil)
+	total = addUsage(total, &assistants.Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25})
+
+	want := assistants.Usage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37}
+	if total == nil || *total != want {
+		t.Errorf("got usage %+v, want %+v", total, want)
+	}
+}
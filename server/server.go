@@ -0,0 +1,329 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultAddress is the address the server listens on when none is configured.
+	DefaultAddress = "127.0.0.1:7070"
+	// defaultShutdownTimeout is the time given to the running requests to end when none is configured.
+	defaultShutdownTimeout = 10 * time.Second
+	// defaultMaxSessions is the maximum number of open sessions when none is configured.
+	defaultMaxSessions = 100
+	// defaultSessionIdleTimeout is how long a session is kept without messages when none is configured.
+	defaultSessionIdleTimeout = time.Hour
+	// readHeaderTimeout is the time given to the clients to send the headers of a request.
+	readHeaderTimeout = 10 * time.Second
+	// maxBodySize is the maximum size in bytes of the body of a request.
+	maxBodySize = 1 << 20
+)
+
+// serverLog is the logger of the server.
+var serverLog = log.WithField("component", "server")
+
+// Server is the HTTP API of Pishia. It exposes sessions, each one with its own assistant, and streams
+// the answers as Server-Sent Events.
+type Server struct {
+	// config is the configuration of the server.
+	config config.Server
+	// sessions are the open sessions.
+	sessions *sessionStore
+	// mux routes the requests to the handlers.
+	mux *http.ServeMux
+}
+
+// NewServer creates a Server. The assistants of the sessions are created from the configuration the
+// core runs with when the session is created.
+func NewServer(config config.Server) *Server {
+	if config.Address == "" {
+		config.Address = DefaultAddress
+	}
+
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	if config.MaxSessions == 0 {
+		config.MaxSessions = defaultMaxSessions
+	}
+
+	if config.SessionIdleTimeout == 0 {
+		config.SessionIdleTimeout = defaultSessionIdleTimeout
+	}
+
+	s := &Server{
+		config:   config,
+		sessions: newSessionStore(config.MaxSessions, config.SessionIdleTimeout),
+		mux:      http.NewServeMux(),
+	}
+
+	s.routes()
+	return s
+}
+
+// routes registers the handlers of the API.
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /sessions", s.handleListSessions)
+	s.mux.HandleFunc("POST /sessions", s.handleCreateSession)
+	s.mux.HandleFunc("GET /sessions/{id}", s.handleGetSession)
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.handleDeleteSession)
+	s.mux.HandleFunc("POST /sessions/{id}/messages", s.handleSendMessage)
+	s.mux.HandleFunc("GET /tools", s.handleListTools)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("GET /v1/models", s.handleListModels)
+}
+
+// Handler returns the handler of the API, with the checks of the Host and Content-Type headers, the
+// authentication and the CORS headers.
+func (s *Server) Handler() http.Handler {
+	return s.withHost(s.withCORS(s.withAuth(withJSON(s.mux))))
+}
+
+// Address returns the address the server listens on.
+func (s *Server) Address() string {
+	return s.config.Address
+}
+
+// Run serves the API until the context is canceled, then waits for the running requests to end, up to
+// the shutdown timeout.
+func (s *Server) Run(ctx context.Context) error {
+	token, err := config.ResolveSecret(s.config.Token)
+	if err != nil {
+		return fmt.Errorf("resolving server.token: %w", err)
+	}
+	s.config.Token = token
+
+	// Any web page can send requests to the API, it is never left open.
+	if s.config.Token == "" {
+		s.config.Token, err = randomToken()
+		if err != nil {
+			return fmt.Errorf("generating a token: %w", err)
+		}
+
+		serverLog.Warnf("No token is set, the clients must send the token %s generated for this run, set server.token to keep one", s.config.Token)
+	}
+
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.config.Address, err)
+	}
+
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.Serve(listener)
+	}()
+
+	serverLog.Infof("Listening on %s", listener.Addr())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	serverLog.Info("Shutting down, waiting for the running requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	err = httpServer.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		httpServer.Close()
+		return fmt.Errorf("the running requests didn't end within %s", s.config.ShutdownTimeout)
+	}
+
+	return err
+}
+
+// withAuth checks the bearer token of the requests, when one is configured. The health check is open.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Token == "" || r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.config.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pishia"`)
+			writeRequestError(w, r, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withHost rejects the requests for another host than the address of the server, the loopback names or
+// server.allowed_hosts. A web page can't reach the API by resolving its own domain name to the address
+// of the server (DNS rebinding).
+func (s *Server) withHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeRequestError(w, r, http.StatusMisdirectedRequest, "host %q is not allowed", r.Host)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost checks if the requests for the host, from the Host header, are served. The IP addresses
+// are always allowed, a page of another site can't send them.
+func (s *Server) allowedHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+
+	if host == "" || net.ParseIP(host) != nil || strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	if name, _, err := net.SplitHostPort(s.config.Address); err == nil && strings.EqualFold(name, host) {
+		return true
+	}
+
+	for _, allowed := range s.config.AllowedHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withJSON rejects the requests with a body that is not JSON. A browser sends a cross-origin request
+// without preflight when its body is text/plain or a form, the API never reads them.
+func withJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeRequestError(w, r, http.StatusUnsupportedMediaType, "the body must be application/json")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withCORS adds the CORS headers for the allowed origins and answers the preflight requests.
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !s.allowedOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedOrigin checks if the origin is allowed to call the API from a browser.
+func (s *Server) allowedOrigin(origin string) bool {
+	for _, allowed := range s.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handleHealth answers the health checks.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// randomToken returns a random bearer token.
+func randomToken() (string, error) {
+	token := make([]byte, 24)
+
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// readJSON decodes the JSON body of the request into v. An empty body is accepted.
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		serverLog.Debugf("Writing the response: %v", err)
+	}
+}
+
+// writeRequestError writes an error in the format of the API of the request: the OpenAI clients expect
+// the errors in the format of the OpenAI API.
+func writeRequestError(w http.ResponseWriter, r *http.Request, status int, format string, args ...interface{}) {
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeOpenAIError(w, status, "invalid_request_error", format, args...)
+		return
+	}
+
+	writeError(w, status, format, args...)
+}
+
+// writeError writes the error as the JSON body of the response.
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
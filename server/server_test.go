@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/config"
+)
+
+// serve sends a request to the handler of a server with the token secret and returns the response.
+func serve(s *Server, method string, url string, host string, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Host = host
+	req.Header.Set("Authorization", "Bearer secret")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestHostCheck(t *testing.T) {
+	s := NewServer(config.Server{Address: "127.0.0.1:7070", Token: "secret", AllowedHosts: []string{"pishia.example.com"}})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"127.0.0.1:7070", true},
+		{"localhost:7070", true},
+		{"LOCALHOST", true},
+		{"[::1]:7070", true},
+		{"192.168.1.10:7070", true},
+		{"pishia.example.com", true},
+		{"attacker.example.com:7070", false},
+		{"localhost.attacker.example.com", false},
+	}
+
+	for _, test := range tests {
+		recorder := serve(s, http.MethodGet, "/health", test.host, "", "")
+
+		if allowed := recorder.Code != http.StatusMisdirectedRequest; allowed != test.allowed {
+			t.Errorf("host %q: got status %d, want allowed %v", test.host, recorder.Code, test.allowed)
+		}
+	}
+}
+
+func TestContentTypeCheck(t *testing.T) {
+	s := NewServer(config.Server{Token: "secret"})
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "multipart/form-data; boundary=x"} {
+		recorder := serve(s, http.MethodPost, "/sessions", "localhost", contentType, `{}`)
+		if recorder.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("content type %q: got status %d, want %d", contentType, recorder.Code, http.StatusUnsupportedMediaType)
+		}
+
+		recorder = serve(s, http.MethodPost, "/v1/chat/completions", "localhost", contentType, `{}`)
+		if recorder.Code != http.StatusUnsupportedMediaType || !strings.Contains(recorder.Body.String(), "invalid_request_error") {
+			t.Errorf("content type %q: got status %d and body %s, want an OpenAI error %d", contentType, recorder.Code, recorder.Body, http.StatusUnsupportedMediaType)
+		}
+	}
+
+	// The JSON bodies reach the handler, it fails without the assistants of the core.
+	recorder := serve(s, http.MethodPost, "/v1/chat/completions", "localhost", "application/json; charset=utf-8", `{"messages":[]}`)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d for an empty conversation", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := NewServer(config.Server{Token: "other"})
+
+	if recorder := serve(s, http.MethodGet, "/sessions", "localhost", "", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d for a wrong token", recorder.Code, http.StatusUnauthorized)
+	}
+
+	if recorder := serve(s, http.MethodGet, "/health", "localhost", "", ""); recorder.Code != http.StatusOK {
+		t.Errorf("got status %d, want the health check to be open", recorder.Code)
+	}
+}
+
+func TestRandomToken(t *testing.T) {
+	first, err := randomToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := randomToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) < 32 || first == second {
+		t.Errorf("got tokens %q and %q, want long and different tokens", first, second)
+	}
+}
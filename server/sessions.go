@@ -0,0 +1,528 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/plugins/assistants"
+	"github.com/Pishia-IA/core/plugins/tools"
+)
+
+// session is a conversation with its own assistant.
+type session struct {
+	// id is the ID of the session.
+	id string
+	// plugin is the assistant plugin of the session.
+	plugin string
+	// model is the model of the session.
+	model string
+	// createdAt is when the session was created.
+	createdAt time.Time
+	// lastUsed is when the session was created or last answered a message.
+	lastUsed time.Time
+	// assistant answers the messages of the session.
+	assistant assistants.Assistant
+
+	// mu protects busy, lastUsed and messages.
+	mu sync.Mutex
+	// busy checks if the assistant is answering a message, a session answers one message at a time.
+	busy bool
+	// messages are the messages of the conversation, updated when an answer is done.
+	messages []assistants.Message
+}
+
+// sessionInfo is the JSON representation of a session.
+type sessionInfo struct {
+	// ID is the ID of the session.
+	ID string `json:"id"`
+	// Plugin is the assistant plugin of the session.
+	Plugin string `json:"plugin"`
+	// Model is the model of the session.
+	Model string `json:"model"`
+	// CreatedAt is when the session was created.
+	CreatedAt time.Time `json:"created_at"`
+	// Busy checks if the assistant is answering a message.
+	Busy bool `json:"busy"`
+	// Messages are the messages of the conversation, only given for a single session.
+	Messages []assistants.Message `json:"messages,omitempty"`
+}
+
+// createSessionRequest is the body of the request creating a session, every field is optional.
+type createSessionRequest struct {
+	// Plugin is the assistant plugin to use instead of the configured one.
+	Plugin string `json:"plugin"`
+	// Model is the model to use instead of the configured one.
+	Model string `json:"model"`
+	// System is the system prompt to use instead of the configured one.
+	System string `json:"system"`
+	// Options are the parameters of the generation overriding the configured ones.
+	Options *generationOptions `json:"options"`
+}
+
+// generationOptions are the parameters of the generation sent in a request, the ones not set keep the
+// configured values.
+type generationOptions struct {
+	// Temperature is the randomness of the answers, from 0 to 2.
+	Temperature *float64 `json:"temperature"`
+	// TopP keeps the most likely tokens up to this cumulative probability, from 0 to 1.
+	TopP *float64 `json:"top_p"`
+	// MaxTokens is the maximum number of tokens of an answer.
+	MaxTokens *int `json:"max_tokens"`
+	// NumCtx is the size of the context window in tokens.
+	NumCtx *int `json:"num_ctx"`
+	// Seed makes the answers reproducible for the same prompt.
+	Seed *int `json:"seed"`
+	// Stop are the sequences that end an answer.
+	Stop []string `json:"stop"`
+	// RepeatPenalty penalizes the repeated tokens.
+	RepeatPenalty *float64 `json:"repeat_penalty"`
+	// Format is the format of the answers, text or json.
+	Format *string `json:"format"`
+}
+
+// apply overrides the parameters of the generation with the options set.
+func (o *generationOptions) apply(generation *config.Generation) {
+	if o.Temperature != nil {
+		generation.Temperature = o.Temperature
+	}
+
+	if o.TopP != nil {
+		generation.TopP = o.TopP
+	}
+
+	if o.MaxTokens != nil {
+		generation.MaxTokens = *o.MaxTokens
+	}
+
+	if o.NumCtx != nil {
+		generation.NumCtx = *o.NumCtx
+	}
+
+	if o.Seed != nil {
+		generation.Seed = o.Seed
+	}
+
+	if o.Stop != nil {
+		generation.Stop = o.Stop
+	}
+
+	if o.RepeatPenalty != nil {
+		generation.RepeatPenalty = o.RepeatPenalty
+	}
+
+	if o.Format != nil {
+		generation.Format = *o.Format
+	}
+}
+
+// sendMessageRequest is the body of the request sending a message to a session.
+type sendMessageRequest struct {
+	// Content is the message.
+	Content string `json:"content"`
+}
+
+// info returns the JSON representation of the session, with the messages if withMessages is set.
+func (s *session) info(withMessages bool) sessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := sessionInfo{
+		ID:        s.id,
+		Plugin:    s.plugin,
+		Model:     s.model,
+		CreatedAt: s.createdAt,
+		Busy:      s.busy,
+	}
+
+	if withMessages {
+		info.Messages = append(make([]assistants.Message, 0, len(s.messages)), s.messages...)
+	}
+
+	return info
+}
+
+// acquire marks the session as busy, it returns false if it already is.
+func (s *session) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy {
+		return false
+	}
+
+	s.busy = true
+	return true
+}
+
+// release marks the session as idle and keeps the messages of the conversation.
+func (s *session) release() {
+	messages := s.assistant.History()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.busy = false
+	s.lastUsed = time.Now()
+	s.messages = messages
+}
+
+// idle checks if the session didn't answer a message for longer than timeout.
+func (s *session) idle(now time.Time, timeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.busy && now.Sub(s.lastUsed) > timeout
+}
+
+// errTooManySessions is returned when a session is added to a full sessionStore.
+var errTooManySessions = errors.New("too many open sessions, delete one first")
+
+// sessionStore keeps the open sessions in memory, deleting the idle ones.
+type sessionStore struct {
+	// mu protects sessions and reserved.
+	mu sync.Mutex
+	// sessions are the open sessions, by ID.
+	sessions map[string]*session
+	// reserved is the number of places reserved for the sessions being created.
+	reserved int
+	// maxSessions is the maximum number of open sessions.
+	maxSessions int
+	// idleTimeout is how long a session is kept without messages.
+	idleTimeout time.Duration
+}
+
+// newSessionStore creates an empty sessionStore.
+func newSessionStore(maxSessions int, idleTimeout time.Duration) *sessionStore {
+	return &sessionStore{
+		sessions:    make(map[string]*session),
+		maxSessions: maxSessions,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Reserve reserves a place for a session before it is created, it returns errTooManySessions if
+// maxSessions are already open or being created. The place is taken by Add or given back by Release.
+func (s *sessionStore) Reserve() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+	if len(s.sessions)+s.reserved >= s.maxSessions {
+		return errTooManySessions
+	}
+
+	s.reserved++
+	return nil
+}
+
+// Release gives back a place reserved for a session that couldn't be created.
+func (s *sessionStore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserved--
+}
+
+// Add adds a session in a place reserved with Reserve.
+func (s *sessionStore) Add(session *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reserved--
+	s.sessions[session.id] = session
+}
+
+// Get gets a session by ID.
+func (s *sessionStore) Get(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+	session, ok := s.sessions[id]
+	return session, ok
+}
+
+// expire deletes the idle sessions, s.mu must be held.
+func (s *sessionStore) expire() {
+	now := time.Now()
+
+	for id, session := range s.sessions {
+		if session.idle(now, s.idleTimeout) {
+			serverLog.WithField("session", id).Info("Idle session deleted")
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// Delete deletes a session, it returns false if it doesn't exist.
+func (s *sessionStore) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	return ok
+}
+
+// List returns the sessions, the oldest first.
+func (s *sessionStore) List() []*session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].createdAt.Before(sessions[j].createdAt)
+	})
+
+	return sessions
+}
+
+// newSession creates a session with its own assistant, from the configuration the core runs with and the
+// overrides of the request.
+func newSession(ctx context.Context, req createSessionRequest) (*session, int, error) {
+	assistant, cfg, status, err := newAssistant(ctx, req)
+	if err != nil {
+		return nil, status, err
+	}
+
+	now := time.Now()
+
+	return &session{
+		id:        core.NewSessionID(),
+		plugin:    cfg.Assistants.Plugin,
+		model:     modelOf(cfg),
+		createdAt: now,
+		lastUsed:  now,
+		assistant: assistant,
+		messages:  assistant.History(),
+	}, http.StatusCreated, nil
+}
+
+// newAssistant creates and sets up an assistant from the configuration the core runs with and the
+// overrides of the request. The model must be installed, it is not pulled. The HTTP status of the error
+// is returned with it.
+func newAssistant(ctx context.Context, req createSessionRequest) (assistants.Assistant, *config.Base, int, error) {
+	current := core.Config()
+	if current == nil {
+		return nil, nil, http.StatusServiceUnavailable, fmt.Errorf("no configuration loaded")
+	}
+
+	cfg := *current
+	if req.Plugin != "" {
+		cfg.Assistants.Plugin = req.Plugin
+	}
+
+	if req.Model != "" {
+		switch cfg.Assistants.Plugin {
+		case "ollama":
+			cfg.Assistants.Ollama.Model = req.Model
+		case "openai":
+			cfg.Assistants.OpenAI.Model = req.Model
+		}
+	}
+
+	if req.System != "" {
+		cfg.Prompts.System = req.System
+	}
+
+	if req.Options != nil {
+		req.Options.apply(&cfg.Assistants.Generation)
+	}
+
+	err := config.Validate(&cfg, core.Names())
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+
+	status, err := checkModel(ctx, &cfg)
+	if err != nil {
+		return nil, nil, status, err
+	}
+
+	assistant, err := assistants.New(&cfg, cfg.Assistants.Plugin)
+	if err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+
+	// A request never pulls a model, the download can take hours: it is done with pishia models pull.
+	if ollama, ok := assistant.(*assistants.Ollama); ok {
+		ollama.NoPull = true
+	}
+
+	err = assistant.Setup(ctx)
+	if err != nil {
+		return nil, nil, http.StatusBadGateway, fmt.Errorf("setting up the assistant: %w", err)
+	}
+
+	return assistant, &cfg, http.StatusOK, nil
+}
+
+// modelOf returns the model of the assistant plugin of the configuration.
+func modelOf(cfg *config.Base) string {
+	if cfg.Assistants.Plugin == "openai" {
+		return cfg.Assistants.OpenAI.Model
+	}
+
+	return cfg.Assistants.Ollama.Model
+}
+
+// handleListSessions lists the open sessions.
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := s.sessions.List()
+
+	infos := make([]sessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, session.info(false))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": infos})
+}
+
+// handleCreateSession creates a session.
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req createSessionRequest
+
+	err := readJSON(r, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	// The place is reserved first, a full server doesn't set up an assistant.
+	err = s.sessions.Reserve()
+	if err != nil {
+		writeError(w, http.StatusTooManyRequests, "%v", err)
+		return
+	}
+
+	session, status, err := newSession(r.Context(), req)
+	if err != nil {
+		s.sessions.Release()
+		writeError(w, status, "%v", err)
+		return
+	}
+
+	s.sessions.Add(session)
+
+	serverLog.WithField("session", session.id).Infof("Session created with the %s model %s", session.plugin, session.model)
+
+	writeJSON(w, status, session.info(false))
+}
+
+// handleGetSession returns a session with its messages.
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.sessions.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "session %q not found", r.PathValue("id"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, session.info(true))
+}
+
+// handleDeleteSession deletes a session. An answer being streamed is not interrupted.
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if !s.sessions.Delete(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, "session %q not found", r.PathValue("id"))
+		return
+	}
+
+	serverLog.WithField("session", r.PathValue("id")).Info("Session deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSendMessage sends a message to the assistant of a session and streams the answer as
+// Server-Sent Events: the events of the assistant, then a message or an error event. The answer stops
+// when the client goes away.
+func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.sessions.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "session %q not found", r.PathValue("id"))
+		return
+	}
+
+	var req sendMessageRequest
+
+	err := readJSON(r, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	if !session.acquire() {
+		writeError(w, http.StatusConflict, "session %q is already answering a message", session.id)
+		return
+	}
+	defer session.release()
+
+	stream := newEventStream(w)
+	session.assistant.SetEventHandler(stream.Send)
+	defer session.assistant.SetEventHandler(nil)
+
+	var answer strings.Builder
+	var answerErr error
+
+	err = session.assistant.SendRequest(r.Context(), content, func(output string, err error) {
+		if err != nil {
+			answerErr = err
+			return
+		}
+
+		answer.WriteString(output)
+		stream.Send(assistants.Event{Type: assistants.EventToken, Content: output})
+	})
+	if err == nil {
+		err = answerErr
+	}
+
+	if err != nil {
+		serverLog.WithField("session", session.id).Warnf("Answering the message: %v", err)
+		stream.Send(assistants.Event{Type: assistants.EventError, Error: err.Error()})
+		return
+	}
+
+	stream.Send(assistants.Event{Type: assistants.EventMessage, Content: strings.TrimSpace(answer.String())})
+}
+
+// toolInfo is the JSON representation of a tool.
+type toolInfo struct {
+	// Name is the name of the tool.
+	Name string `json:"name"`
+	// Description is the description of the tool.
+	Description string `json:"description"`
+}
+
+// handleListTools lists the tools the assistants can call.
+func (s *Server) handleListTools(w http.ResponseWriter, r *http.Request) {
+	repository := tools.GetRepository()
+
+	infos := make([]toolInfo, 0)
+	if repository != nil {
+		for _, name := range tools.Names() {
+			if tool, ok := repository.Get(name); ok {
+				infos = append(infos, toolInfo{Name: name, Description: tool.Description()})
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tools": infos})
+}
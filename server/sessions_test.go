@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Pishia-IA/core/config"
+	"github.com/Pishia-IA/core/core"
+	"github.com/Pishia-IA/core/thirdparty/ollama/ollamatest"
+)
+
+// addSession reserves a place and adds a session to the store.
+func addSession(t *testing.T, store *sessionStore, session *session) {
+	t.Helper()
+
+	err := store.Reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Add(session)
+}
+
+func TestSessionStoreLimit(t *testing.T) {
+	store := newSessionStore(2, time.Hour)
+
+	addSession(t, store, &session{id: "a", lastUsed: time.Now()})
+
+	// A place being created counts like an open session.
+	err := store.Reserve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Reserve(); !errors.Is(err, errTooManySessions) {
+		t.Fatalf("got error %v, want %v", err, errTooManySessions)
+	}
+
+	store.Release()
+	addSession(t, store, &session{id: "b", lastUsed: time.Now()})
+
+	if err := store.Reserve(); !errors.Is(err, errTooManySessions) {
+		t.Fatalf("got error %v, want %v", err, errTooManySessions)
+	}
+
+	store.Delete("a")
+	addSession(t, store, &session{id: "c", lastUsed: time.Now()})
+}
+
+func TestSessionStoreIdleTimeout(t *testing.T) {
+	store := newSessionStore(2, time.Minute)
+	old := time.Now().Add(-2 * time.Minute)
+
+	addSession(t, store, &session{id: "idle", lastUsed: old})
+	addSession(t, store, &session{id: "busy", lastUsed: old, busy: true})
+
+	if _, ok := store.Get("idle"); ok {
+		t.Error("the idle session must be deleted")
+	}
+
+	if _, ok := store.Get("busy"); !ok {
+		t.Error("the session answering a message must be kept")
+	}
+
+	if err := store.Reserve(); err != nil {
+		t.Fatalf("the idle session must free its place: %v", err)
+	}
+}
+
+func TestCreateSessionFull(t *testing.T) {
+	ollama := ollamatest.NewServer("llama3")
+	defer ollama.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Assistants.Ollama.Endpoint = ollama.URL
+	cfg.Assistants.Ollama.Model = "llama3"
+
+	err := core.Reload(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(config.Server{Token: "secret", MaxSessions: 1})
+
+	// A session that can't be created gives its place back.
+	resp := serve(s, http.MethodPost, "/sessions", "127.0.0.1:7070", "application/json", `{"model": "missing"}`)
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", resp.Code, http.StatusNotFound)
+	}
+
+	addSession(t, s.sessions, &session{id: "open", lastUsed: time.Now()})
+
+	requests := len(ollama.Requests())
+
+	resp = serve(s, http.MethodPost, "/sessions", "127.0.0.1:7070", "application/json", `{"model": "llama3"}`)
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want %d", resp.Code, http.StatusTooManyRequests)
+	}
+
+	if n := len(ollama.Requests()); n != requests {
+		t.Errorf("a full server sent %d requests to the backend, want none", n-requests)
+	}
+}
+
+func TestNewAssistantDoesNotPull(t *testing.T) {
+	ollama := ollamatest.NewServer("llama3", "mistral")
+	defer ollama.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Assistants.Ollama.Endpoint = ollama.URL
+	cfg.Assistants.Ollama.Model = "llama3"
+
+	err := core.Reload(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, status, err := newAssistant(context.Background(), createSessionRequest{Model: "mistral"})
+	if err != nil {
+		t.Fatalf("an installed model must be accepted: %v", err)
+	}
+
+	_, _, status, err = newAssistant(context.Background(), createSessionRequest{Model: "missing"})
+	if err == nil || status != http.StatusNotFound {
+		t.Fatalf("got status %d and error %v, want %d", status, err, http.StatusNotFound)
+	}
+
+	for _, request := range ollama.Requests() {
+		if request.Path == "/api/pull" {
+			t.Fatal("a request must never pull a model")
+		}
+	}
+}
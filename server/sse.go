@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Pishia-IA/core/plugins/assistants"
+)
+
+// eventStream writes the events of an assistant as Server-Sent Events, the type of the event is the SSE
+// event name and the event itself the JSON data.
+type eventStream struct {
+	// w is the response the events are written to.
+	w http.ResponseWriter
+	// controller flushes the events as they are written.
+	controller *http.ResponseController
+	// mu serializes the events, they can be sent from the handler and the callback of the assistant.
+	mu sync.Mutex
+	// closed checks if the client went away, the next events are dropped.
+	closed bool
+}
+
+// newEventStream starts the Server-Sent Events response.
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Proxies like nginx buffer the responses unless told otherwise.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+	}
+	stream.controller.Flush()
+
+	return stream
+}
+
+// Send writes an event of the assistant and flushes it to the client.
+func (s *eventStream) Send(event assistants.Event) {
+	s.SendJSON(event.Type, event)
+}
+
+// SendJSON writes v as the JSON data of an event, without an event name if name is empty.
+func (s *eventStream) SendJSON(name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		serverLog.Warnf("Encoding the %s event: %v", name, err)
+		return
+	}
+
+	s.write(name, data)
+}
+
+// write writes an event and flushes it to the client.
+func (s *eventStream) write(name string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	var err error
+	if name != "" {
+		_, err = fmt.Fprintf(s.w, "event: %s\n", name)
+	}
+
+	if err == nil {
+		_, err = fmt.Fprintf(s.w, "data: %s\n\n", data)
+	}
+
+	if err == nil {
+		err = s.controller.Flush()
+	}
+
+	if err != nil {
+		serverLog.Debugf("The client went away: %v", err)
+		s.closed = true
+	}
+}
@@ -0,0 +1,350 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+	"github.com/Pishia-IA/core/thirdparty/ollama/ollamatest"
+)
+
+// newClient starts a fake Ollama with the models and returns it with a client.
+func newClient(t *testing.T, models ...string) (*ollamatest.Server, *ollama.OllamaClient) {
+	t.Helper()
+
+	server := ollamatest.NewServer(models...)
+	t.Cleanup(server.Close)
+
+	return server, ollama.NewOllamaClient(server.URL)
+}
+
+func TestVersion(t *testing.T) {
+	server, client := newClient(t)
+	server.SetVersion("0.9.1")
+
+	version, err := client.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version.Version != "0.9.1" {
+		t.Errorf("got version %q, want %q", version.Version, "0.9.1")
+	}
+}
+
+func TestListModels(t *testing.T) {
+	_, client := newClient(t, "mistral", "llama3")
+
+	list, err := client.ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(list.Models))
+	for _, model := range list.Models {
+		names = append(names, model.Name)
+	}
+
+	if want := []string{"llama3", "mistral"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got models %v, want %v", names, want)
+	}
+}
+
+func TestShowModel(t *testing.T) {
+	_, client := newClient(t, "llama3")
+
+	show, err := client.ShowModel(context.Background(), &ollama.ShowModelRequest{Name: "llama3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if show.ModelFile != "FROM llama3" {
+		t.Errorf("got Modelfile %q, want %q", show.ModelFile, "FROM llama3")
+	}
+
+	_, err = client.ShowModel(context.Background(), &ollama.ShowModelRequest{Name: "missing"})
+	var statusErr *ollama.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want a %d status error", err, http.StatusNotFound)
+	}
+}
+
+func TestManageModels(t *testing.T) {
+	server, client := newClient(t, "llama3")
+	ctx := context.Background()
+
+	err := client.CopyModel(ctx, &ollama.CopyModelRequest{Source: "llama3", Destination: "copy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.CreateModel(ctx, &ollama.CreateModelRequest{Name: "custom", Modelfile: "FROM llama3\nSYSTEM be brief"}, func(*ollama.ProgressResponse) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pushed []string
+	err = client.PushModel(ctx, &ollama.PushModelRequest{Name: "custom"}, func(status *ollama.ProgressResponse) error {
+		pushed = append(pushed, status.Status)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pushed) == 0 || pushed[len(pushed)-1] != "success" {
+		t.Errorf("got push statuses %v, want success last", pushed)
+	}
+
+	err = client.DeleteModel(ctx, &ollama.DeleteModelRequest{Name: "copy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.HasModel("custom") || server.HasModel("copy") {
+		t.Error("custom must be created and copy deleted")
+	}
+
+	err = client.DeleteModel(ctx, &ollama.DeleteModelRequest{Name: "copy"})
+	if err == nil {
+		t.Error("deleting a missing model must fail")
+	}
+}
+
+func TestPullModel(t *testing.T) {
+	server, client := newClient(t)
+
+	var statuses []string
+	err := client.PullModelStream(context.Background(), &ollama.PullModelRequest{Name: "llama3"}, func(status *ollama.PullModelResponse) error {
+		statuses = append(statuses, status.Status)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.HasModel("llama3") {
+		t.Error("the pulled model must be added")
+	}
+
+	if len(statuses) == 0 || statuses[0] != "pulling manifest" || statuses[len(statuses)-1] != "success" {
+		t.Errorf("got statuses %v, want pulling manifest first and success last", statuses)
+	}
+}
+
+func TestPullModelInterrupted(t *testing.T) {
+	server, client := newClient(t)
+	server.InterruptPulls(1)
+
+	err := client.PullModelStream(context.Background(), &ollama.PullModelRequest{Name: "llama3"}, func(*ollama.PullModelResponse) error { return nil })
+	if !errors.Is(err, ollama.ErrPullInterrupted) {
+		t.Fatalf("got error %v, want %v", err, ollama.ErrPullInterrupted)
+	}
+
+	if server.HasModel("llama3") {
+		t.Error("an interrupted pull must not add the model")
+	}
+}
+
+func TestPullModelWithRetryResumes(t *testing.T) {
+	ollama.SetPullRetryDelay(t, 0)
+
+	server, client := newClient(t)
+	server.InterruptPulls(2)
+
+	var completed []int64
+	err := client.PullModelWithRetry(context.Background(), &ollama.PullModelRequest{Name: "llama3"}, 3, func(status *ollama.PullModelResponse) error {
+		if status.Total > 0 {
+			completed = append(completed, status.Completed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.HasModel("llama3") {
+		t.Error("the pulled model must be added")
+	}
+
+	// The attempts after the first one start from the bytes already downloaded.
+	for i, bytes := range completed[1:] {
+		if bytes < completed[i] {
+			t.Fatalf("got progress %v, want the pull to resume where it stopped", completed)
+		}
+	}
+
+	pulls := 0
+	for _, request := range server.Requests() {
+		if request.Path == "/api/pull" {
+			pulls++
+		}
+	}
+
+	if pulls != 3 {
+		t.Errorf("got %d pulls, want 3", pulls)
+	}
+}
+
+func TestChat(t *testing.T) {
+	_, client := newClient(t, "llama3")
+
+	resp, err := client.Chat(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "hello"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[llama3] echo: hello"; resp.Message.Content != want {
+		t.Errorf("got answer %q, want %q", resp.Message.Content, want)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	_, client := newClient(t, "llama3")
+
+	chunks, errs, err := client.ChatStream(context.Background(), &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "hello world"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var answer strings.Builder
+	done := false
+	for chunk := range chunks {
+		answer.WriteString(chunk.Message.Content)
+		done = chunk.Done
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+
+	if want := "[llama3] echo: hello world"; answer.String() != want || !done {
+		t.Errorf("got answer %q (done %v), want %q", answer.String(), done, want)
+	}
+}
+
+func TestChatStreamCanceled(t *testing.T) {
+	server, client := newClient(t, "llama3")
+	server.SetReply(func(model string, messages []ollama.Message) string {
+		return strings.Repeat("word ", 10000)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks, errs, err := client.ChatStream(ctx, &ollama.ChatRequest{
+		Model:    "llama3",
+		Messages: []ollama.Message{{Role: "user", Content: "hello"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-chunks
+	cancel()
+
+	for chunk := range chunks {
+		if chunk.Done {
+			t.Fatal("the answer must stop when the context is canceled")
+		}
+	}
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	_, client := newClient(t, "llama3")
+
+	resp, err := client.Generate(context.Background(), &ollama.GenerateRequest{Model: "llama3", Prompt: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[llama3] echo: hello"; resp.Response != want || !resp.Done || len(resp.Context) == 0 {
+		t.Errorf("got %q (done %v, context %v), want %q with a context", resp.Response, resp.Done, resp.Context, want)
+	}
+
+	var answer strings.Builder
+	err = client.GenerateStream(context.Background(), &ollama.GenerateRequest{Model: "llama3", Prompt: "hello"}, func(resp *ollama.GenerateResponse) error {
+		answer.WriteString(resp.Response)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[llama3] echo: hello"; answer.String() != want {
+		t.Errorf("got streamed answer %q, want %q", answer.String(), want)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	_, client := newClient(t, "nomic-embed-text")
+
+	resp, err := client.Embed(context.Background(), &ollama.EmbedRequest{Model: "nomic-embed-text", Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Embeddings) != 2 || !reflect.DeepEqual(resp.Embeddings[0], ollamatest.Embedding("a")) {
+		t.Errorf("got embeddings %v, want the embeddings of a and b", resp.Embeddings)
+	}
+}
+
+func TestListRunningModels(t *testing.T) {
+	_, client := newClient(t, "llama3", "mistral")
+
+	_, err := client.Chat(context.Background(), &ollama.ChatRequest{Model: "llama3", Messages: []ollama.Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := client.ListRunningModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(running.Models) != 1 || running.Models[0].Name != "llama3" {
+		t.Errorf("got running models %v, want llama3", running.Models)
+	}
+}
+
+func TestOptionsExtra(t *testing.T) {
+	temperature := 0.2
+	options := &ollama.Options{
+		Temperature: &temperature,
+		Extra:       map[string]interface{}{"mirostat": 2, "temperature": 1.5},
+	}
+
+	data, err := json.Marshal(ollama.ChatRequest{Model: "llama3", Options: options})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req struct {
+		Options map[string]interface{} `json:"options"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"mirostat": 2.0, "temperature": 0.2}
+	if !reflect.DeepEqual(req.Options, want) {
+		t.Errorf("got options %v, want %v", req.Options, want)
+	}
+}
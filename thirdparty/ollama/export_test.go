@@ -0,0 +1,14 @@
+package ollama
+
+import (
+	"testing"
+	"time"
+)
+
+// SetPullRetryDelay sets the delay between the attempts of a pull for the test, for the tests of package
+// ollama_test.
+func SetPullRetryDelay(t *testing.T, delay time.Duration) {
+	previous := pullRetryDelay
+	pullRetryDelay = delay
+	t.Cleanup(func() { pullRetryDelay = previous })
+}
@@ -0,0 +1,147 @@
+package ollama
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// GenerateRequest is a request to complete a prompt.
+type GenerateRequest struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Prompt is the prompt to complete.
+	Prompt string `json:"prompt"`
+	// Suffix is the text after the completion, for the models that fill in the middle.
+	Suffix string `json:"suffix,omitempty"`
+	// Images are the images of the prompt encoded in base64, for the multimodal models.
+	Images []string `json:"images,omitempty"`
+	// Format is the format of the answer, json or a JSON schema.
+	Format string `json:"format,omitempty"`
+	// System is the system prompt, it overrides the one of the model.
+	System string `json:"system,omitempty"`
+	// Template is the prompt template, it overrides the one of the model.
+	Template string `json:"template,omitempty"`
+	// Context is the context returned by a previous request, to continue the conversation.
+	Context []int `json:"context,omitempty"`
+	// Raw sends the prompt as it is, without applying the template.
+	Raw bool `json:"raw,omitempty"`
+	// KeepAlive is how long the model stays in memory after the request, like 5m.
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// Options are the parameters of the model, like temperature.
+	Options *Options `json:"options,omitempty"`
+	// Stream streams the completion.
+	Stream bool `json:"stream"`
+}
+
+// GenerateResponse is a response to complete a prompt, or a part of it when streaming.
+type GenerateResponse struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// CreatedAt is when the response was created.
+	CreatedAt time.Time `json:"created_at"`
+	// Response is the completion, or a part of it when streaming.
+	Response string `json:"response"`
+	// Done is set on the last response.
+	Done bool `json:"done"`
+	// DoneReason is why the completion ended, like stop or length.
+	DoneReason string `json:"done_reason,omitempty"`
+	// Context is the encoding of the conversation, to send in the next request to continue it.
+	Context []int `json:"context,omitempty"`
+	// TotalDuration is the time spent on the request.
+	TotalDuration time.Duration `json:"total_duration,omitempty"`
+	// LoadDuration is the time spent loading the model.
+	LoadDuration time.Duration `json:"load_duration,omitempty"`
+	// PromptEvalCount is the number of tokens of the prompt.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	// PromptEvalDuration is the time spent evaluating the prompt.
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	// EvalCount is the number of tokens of the completion.
+	EvalCount int `json:"eval_count,omitempty"`
+	// EvalDuration is the time spent generating the completion.
+	EvalDuration time.Duration `json:"eval_duration,omitempty"`
+	// Error is the error of the completion, when streaming.
+	Error string `json:"error,omitempty"`
+}
+
+// EmbedRequest is a request to compute the embeddings of texts.
+type EmbedRequest struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Input are the texts to embed.
+	Input []string `json:"input"`
+	// Truncate truncates the texts that don't fit in the context, an error is returned otherwise. It is
+	// true when not set.
+	Truncate *bool `json:"truncate,omitempty"`
+	// KeepAlive is how long the model stays in memory after the request, like 5m.
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// Options are the parameters of the model.
+	Options *Options `json:"options,omitempty"`
+}
+
+// EmbedResponse is a response to compute the embeddings of texts.
+type EmbedResponse struct {
+	// Model is the name of the model.
+	Model string `json:"model"`
+	// Embeddings are the embeddings of the texts, in the same order.
+	Embeddings [][]float32 `json:"embeddings"`
+	// TotalDuration is the time spent on the request.
+	TotalDuration time.Duration `json:"total_duration,omitempty"`
+	// LoadDuration is the time spent loading the model.
+	LoadDuration time.Duration `json:"load_duration,omitempty"`
+	// PromptEvalCount is the number of tokens of the texts.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+}
+
+// EncodeImage encodes an image for the Images of a GenerateRequest.
+func EncodeImage(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Generate completes a prompt.
+func (c *OllamaClient) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	req.Stream = false
+
+	var generateResp GenerateResponse
+
+	err := c.do(ctx, http.MethodPost, "/api/generate", req, &generateResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generateResp, nil
+}
+
+// GenerateStream completes a prompt, calling fn for every part of the completion until it is done. The
+// completion stops with the error returned by fn, if any.
+func (c *OllamaClient) GenerateStream(ctx context.Context, req *GenerateRequest, fn func(*GenerateResponse) error) error {
+	req.Stream = true // Force streaming
+
+	return stream(ctx, c, "/api/generate", req, func(generateResp *GenerateResponse) (bool, error) {
+		if generateResp.Error != "" {
+			return false, errors.New(generateResp.Error)
+		}
+
+		err := fn(generateResp)
+		return generateResp.Done, err
+	})
+}
+
+// Embed computes the embeddings of texts.
+func (c *OllamaClient) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	var embedResp EmbedResponse
+
+	err := c.do(ctx, http.MethodPost, "/api/embed", req, &embedResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embedResp.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(embedResp.Embeddings))
+	}
+
+	return &embedResp, nil
+}
@@ -0,0 +1,388 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PullStatusSuccess is the last status of a pull, sent when the model is ready.
+const PullStatusSuccess = "success"
+
+var (
+	// ErrPullInterrupted is returned when the stream of a pull ends before the model is ready, pulling
+	// it again resumes the download.
+	ErrPullInterrupted = errors.New("the download was interrupted")
+
+	// pullRetryDelay is the delay before pulling a model again after a failure, it grows with every
+	// attempt.
+	pullRetryDelay = 2 * time.Second
+)
+
+// StatusError is the error of a response with an unexpected status.
+type StatusError struct {
+	// StatusCode is the status of the response.
+	StatusCode int
+	// Message is the error sent by the Ollama, if any.
+	Message string
+}
+
+// Error returns the status and the message of the error.
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("unexpected status code: %d: %s", e.StatusCode, e.Message)
+}
+
+// ModelDetails are the details of a model.
+type ModelDetails struct {
+	// Format is the format of the model file, like gguf.
+	Format string `json:"format"`
+	// Family is the family of the model, like llama.
+	Family string `json:"family"`
+	// ParameterSize is the number of parameters of the model, like 7B.
+	ParameterSize string `json:"parameter_size"`
+	// QuantizationLevel is the quantization of the model, like Q4_0.
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// Model is a model available locally.
+type Model struct {
+	// Name is the name of the model.
+	Name string `json:"name"`
+	// ModifiedAt is the time the model was last changed.
+	ModifiedAt time.Time `json:"modified_at"`
+	// Size is the size of the model in bytes.
+	Size int64 `json:"size"`
+	// Digest is the digest of the model.
+	Digest string `json:"digest"`
+	// Details are the details of the model.
+	Details ModelDetails `json:"details"`
+}
+
+// ListModelsResponse is a response to list the local models.
+type ListModelsResponse struct {
+	// Models are the local models.
+	Models []Model `json:"models"`
+}
+
+// RunningModel is a model loaded in memory.
+type RunningModel struct {
+	// Name is the name of the model.
+	Name string `json:"name"`
+	// Size is the size of the model in memory in bytes.
+	Size int64 `json:"size"`
+	// SizeVRAM is the size of the model in the memory of the GPU in bytes.
+	SizeVRAM int64 `json:"size_vram"`
+	// Digest is the digest of the model.
+	Digest string `json:"digest"`
+	// Details are the details of the model.
+	Details ModelDetails `json:"details"`
+	// ExpiresAt is the time the model is unloaded from memory.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListRunningModelsResponse is a response to list the models loaded in memory.
+type ListRunningModelsResponse struct {
+	// Models are the models loaded in memory.
+	Models []RunningModel `json:"models"`
+}
+
+// DeleteModelRequest is a request to delete a model.
+type DeleteModelRequest struct {
+	// Name is the name of the model.
+	Name string `json:"name"`
+}
+
+// CopyModelRequest is a request to copy a model.
+type CopyModelRequest struct {
+	// Source is the name of the model to copy.
+	Source string `json:"source"`
+	// Destination is the name of the copy.
+	Destination string `json:"destination"`
+}
+
+// ProgressResponse is a status of a streamed create, pull or push.
+type ProgressResponse = PullModelResponse
+
+// CreateModelRequest is a request to create a model.
+type CreateModelRequest struct {
+	// Name is the name of the model to create.
+	Name string `json:"name"`
+	// From is the model the new one is based on.
+	From string `json:"from,omitempty"`
+	// Modelfile is the Modelfile of the model, for the versions of the Ollama that need one.
+	Modelfile string `json:"modelfile,omitempty"`
+	// System is the system prompt of the model.
+	System string `json:"system,omitempty"`
+	// Template is the prompt template of the model.
+	Template string `json:"template,omitempty"`
+	// Parameters are the default parameters of the model, like temperature.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Quantize is the quantization applied to the model, like q4_K_M.
+	Quantize string `json:"quantize,omitempty"`
+	// Stream streams the progress of the creation.
+	Stream bool `json:"stream"`
+}
+
+// PushModelRequest is a request to upload a model to a registry.
+type PushModelRequest struct {
+	// Name is the name of the model, with its namespace, like user/model:tag.
+	Name string `json:"name"`
+	// Insecure allows an insecure connection to the registry.
+	Insecure bool `json:"insecure,omitempty"`
+	// Stream streams the progress of the upload.
+	Stream bool `json:"stream"`
+}
+
+// VersionResponse is a response to get the version of the Ollama.
+type VersionResponse struct {
+	// Version is the version of the Ollama.
+	Version string `json:"version"`
+}
+
+// ListModels lists the local models.
+func (c *OllamaClient) ListModels(ctx context.Context) (*ListModelsResponse, error) {
+	var listModelsResp ListModelsResponse
+
+	err := c.do(ctx, http.MethodGet, "/api/tags", nil, &listModelsResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listModelsResp, nil
+}
+
+// ListRunningModels lists the models loaded in memory.
+func (c *OllamaClient) ListRunningModels(ctx context.Context) (*ListRunningModelsResponse, error) {
+	var listRunningModelsResp ListRunningModelsResponse
+
+	err := c.do(ctx, http.MethodGet, "/api/ps", nil, &listRunningModelsResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listRunningModelsResp, nil
+}
+
+// DeleteModel deletes a model.
+func (c *OllamaClient) DeleteModel(ctx context.Context, req *DeleteModelRequest) error {
+	return c.do(ctx, http.MethodDelete, "/api/delete", req, nil)
+}
+
+// CopyModel copies a model.
+func (c *OllamaClient) CopyModel(ctx context.Context, req *CopyModelRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/copy", req, nil)
+}
+
+// PullModelStream pulls a model, calling progress for every status sent by the Ollama until the pull
+// is done. The pull stops with the error returned by progress, if any. When the stream ends before the
+// success status, the error wraps ErrPullInterrupted.
+func (c *OllamaClient) PullModelStream(ctx context.Context, req *PullModelRequest, progress func(*PullModelResponse) error) error {
+	req.Stream = true // Force streaming
+
+	err := c.streamProgress(ctx, "/api/pull", req, progress)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("pulling %s: %w: %v", req.Name, ErrPullInterrupted, err)
+	}
+
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("pulling %s: %w", req.Name, err)
+	}
+
+	return err
+}
+
+// PullModelWithRetry pulls a model like PullModelStream, pulling it again when the download is
+// interrupted, the Ollama can't be reached or it fails with a server error, up to attempts times. The
+// Ollama keeps the parts of the layers already downloaded, so the pull resumes where it stopped.
+func (c *OllamaClient) PullModelWithRetry(ctx context.Context, req *PullModelRequest, attempts int, progress func(*PullModelResponse) error) error {
+	var err error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = c.PullModelStream(ctx, req, progress)
+		if ctx.Err() != nil || !isTemporary(err) || attempt == attempts {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * pullRetryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
+
+// isTemporary checks if the error of a pull may not happen again: an interrupted download, an error
+// reaching the Ollama or a server error. The errors of the request, like an unknown model, are not.
+func isTemporary(err error) bool {
+	var statusErr *StatusError
+	var urlErr *url.Error
+
+	switch {
+	case errors.Is(err, ErrPullInterrupted):
+		return true
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &urlErr):
+		return true
+	}
+
+	return false
+}
+
+// CreateModel creates a model from another one or from a Modelfile, calling progress for every status
+// sent by the Ollama until the model is created.
+func (c *OllamaClient) CreateModel(ctx context.Context, req *CreateModelRequest, progress func(*ProgressResponse) error) error {
+	req.Stream = true // Force streaming
+
+	err := c.streamProgress(ctx, "/api/create", req, progress)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("creating %s: %w", req.Name, err)
+	}
+
+	return err
+}
+
+// PushModel uploads a model to a registry, calling progress for every status sent by the Ollama until
+// the model is uploaded.
+func (c *OllamaClient) PushModel(ctx context.Context, req *PushModelRequest, progress func(*ProgressResponse) error) error {
+	req.Stream = true // Force streaming
+
+	err := c.streamProgress(ctx, "/api/push", req, progress)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("pushing %s: %w", req.Name, err)
+	}
+
+	return err
+}
+
+// Version returns the version of the Ollama.
+func (c *OllamaClient) Version(ctx context.Context) (*VersionResponse, error) {
+	var versionResp VersionResponse
+
+	err := c.do(ctx, http.MethodGet, "/api/version", nil, &versionResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &versionResp, nil
+}
+
+// streamProgress sends a create, pull or push request and calls progress for every status until the
+// success status. When the stream ends before, the error wraps io.ErrUnexpectedEOF.
+func (c *OllamaClient) streamProgress(ctx context.Context, path string, body interface{}, progress func(*ProgressResponse) error) error {
+	return stream(ctx, c, path, body, func(status *ProgressResponse) (bool, error) {
+		if status.Error != "" {
+			return false, errors.New(status.Error)
+		}
+
+		err := progress(status)
+		if err != nil {
+			return false, err
+		}
+
+		return status.Status == PullStatusSuccess, nil
+	})
+}
+
+// stream sends a request with a JSON body and decodes the NDJSON response, calling each for every value
+// until it returns true. When the stream ends before, the error wraps io.ErrUnexpectedEOF.
+func stream[T any](ctx context.Context, c *OllamaClient, path string, body interface{}, each func(*T) (bool, error)) error {
+	resp, err := c.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var value T
+
+		err := decoder.Decode(&value)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %v", io.ErrUnexpectedEOF, err)
+		}
+
+		done, err := each(&value)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// do sends a request with a JSON body, if any, and decodes the JSON response into out, if any.
+func (c *OllamaClient) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		reqJSON, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewBuffer(reqJSON)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.Endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// statusError returns the error of a response with an unexpected status, with the message sent by the
+// Ollama if there is one.
+func statusError(resp *http.Response) error {
+	var errorResp struct {
+		Error string `json:"error"`
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &errorResp) == nil && errorResp.Error != "" {
+		return &StatusError{StatusCode: resp.StatusCode, Message: errorResp.Error}
+	}
+
+	return &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+}
@@ -0,0 +1,110 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// failingTransport fails the first requests as if the Ollama couldn't be reached.
+type failingTransport struct {
+	failures atomic.Int32
+}
+
+// RoundTrip fails while there are failures left, then sends the request.
+func (t *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.failures.Add(-1) >= 0 {
+		return nil, errors.New("connection refused")
+	}
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// pullServer answers the pulls with the status codes, then with a successful pull.
+func pullServer(t *testing.T, statuses ...int) (*OllamaClient, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			io.WriteString(w, `{"error":"failure"}`)
+			return
+		}
+
+		io.WriteString(w, `{"status":"pulling manifest"}`+"\n"+`{"status":"success"}`+"\n")
+	}))
+	t.Cleanup(server.Close)
+
+	delay := pullRetryDelay
+	pullRetryDelay = 0
+	t.Cleanup(func() { pullRetryDelay = delay })
+
+	return NewOllamaClient(server.URL), &requests
+}
+
+func TestPullModelWithRetryServerErrors(t *testing.T) {
+	client, requests := pullServer(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+
+	err := client.PullModelWithRetry(context.Background(), &PullModelRequest{Name: "llama3"}, 3, func(*PullModelResponse) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestPullModelWithRetryClientError(t *testing.T) {
+	client, requests := pullServer(t, http.StatusNotFound)
+
+	err := client.PullModelWithRetry(context.Background(), &PullModelRequest{Name: "unknown"}, 3, func(*PullModelResponse) error { return nil })
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("got error %v, want a 404 StatusError", err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want the client error not to be retried", n)
+	}
+}
+
+func TestPullModelWithRetryTransportError(t *testing.T) {
+	client, requests := pullServer(t)
+
+	transport := &failingTransport{}
+	transport.failures.Store(2)
+	client.HTTPClient = &http.Client{Transport: transport}
+
+	err := client.PullModelWithRetry(context.Background(), &PullModelRequest{Name: "llama3"}, 3, func(*PullModelResponse) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("got %d requests to the server, want 1 after the 2 transport errors", n)
+	}
+}
+
+func TestPullModelWithRetryGivesUp(t *testing.T) {
+	client, requests := pullServer(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	err := client.PullModelWithRetry(context.Background(), &PullModelRequest{Name: "llama3"}, 2, func(*PullModelResponse) error { return nil })
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got error %v, want a 502 StatusError", err)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("got %d requests, want 2 attempts", n)
+	}
+}
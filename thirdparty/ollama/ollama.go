@@ -2,10 +2,21 @@ package ollama
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout is the default timeout to connect to the Ollama.
+	DefaultDialTimeout = 10 * time.Second
+	// DefaultResponseHeaderTimeout is the default time to wait for the Ollama to answer a request. It is
+	// long because the Ollama answers once the model is loaded in memory.
+	DefaultResponseHeaderTimeout = 5 * time.Minute
 )
 
 // OllamaClient is a client for the Ollama.
@@ -16,11 +27,40 @@ type OllamaClient struct {
 	HTTPClient *http.Client
 }
 
-// NewOllamaClient creates a new OllamaClient.
+// Timeouts are the timeouts of the requests to the Ollama, zero means the default one.
+type Timeouts struct {
+	// Dial is the timeout to connect to the Ollama.
+	Dial time.Duration
+	// ResponseHeader is the time to wait for the Ollama to start answering a request, the body can be
+	// streamed for longer.
+	ResponseHeader time.Duration
+}
+
+// NewOllamaClient creates a new OllamaClient with the default timeouts.
 func NewOllamaClient(endpoint string) *OllamaClient {
+	return NewOllamaClientWithTimeouts(endpoint, Timeouts{})
+}
+
+// NewOllamaClientWithTimeouts creates a new OllamaClient with the timeouts.
+func NewOllamaClientWithTimeouts(endpoint string, timeouts Timeouts) *OllamaClient {
+	if timeouts.Dial <= 0 {
+		timeouts.Dial = DefaultDialTimeout
+	}
+
+	if timeouts.ResponseHeader <= 0 {
+		timeouts.ResponseHeader = DefaultResponseHeaderTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Dial,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = timeouts.ResponseHeader
+
 	return &OllamaClient{
 		Endpoint:   endpoint,
-		HTTPClient: &http.Client{},
+		HTTPClient: &http.Client{Transport: transport},
 	}
 }
 
@@ -40,17 +80,17 @@ type ShowModelResponse struct {
 
 	// Template	is the template
 	Template string `json:"template"`
-}
 
-// ShowModel shows a model.
-func (c *OllamaClient) ShowModel(req *ShowModelRequest) (*ShowModelResponse, error) {
-	reqJSON, err := json.Marshal(req)
+	// License is the license of the model.
+	License string `json:"license"`
 
-	if err != nil {
-		return nil, err
-	}
+	// Details are the details of the model.
+	Details ModelDetails `json:"details"`
+}
 
-	resp, err := c.HTTPClient.Post(c.Endpoint+"/api/show", "application/json", bytes.NewBuffer(reqJSON))
+// ShowModel shows a model.
+func (c *OllamaClient) ShowModel(ctx context.Context, req *ShowModelRequest) (*ShowModelResponse, error) {
+	resp, err := c.post(ctx, "/api/show", req)
 
 	if err != nil {
 		return nil, err
@@ -59,7 +99,7 @@ func (c *OllamaClient) ShowModel(req *ShowModelRequest) (*ShowModelResponse, err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	var showModelResp ShowModelResponse
@@ -81,21 +121,23 @@ type PullModelRequest struct {
 	Stream bool `json:"stream"`
 }
 
-// PullModelResponse is a response to pull a model.
+// PullModelResponse is a response to pull a model, or a progress of the pull when streaming.
 type PullModelResponse struct {
 	// Status is the status of the model.
 	Status string `json:"status"`
+	// Digest is the digest of the layer being downloaded.
+	Digest string `json:"digest,omitempty"`
+	// Total is the size of the layer in bytes.
+	Total int64 `json:"total,omitempty"`
+	// Completed is the number of bytes of the layer downloaded.
+	Completed int64 `json:"completed,omitempty"`
+	// Error is the error of the pull, when streaming.
+	Error string `json:"error,omitempty"`
 }
 
 // PullModel pulls a model.
-func (c *OllamaClient) PullModel(req *PullModelRequest) (*PullModelResponse, error) {
-	reqJSON, err := json.Marshal(req)
-
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.HTTPClient.Post(c.Endpoint+"/api/pull", "application/json", bytes.NewBuffer(reqJSON))
+func (c *OllamaClient) PullModel(ctx context.Context, req *PullModelRequest) (*PullModelResponse, error) {
+	resp, err := c.post(ctx, "/api/pull", req)
 
 	if err != nil {
 		return nil, err
@@ -104,7 +146,7 @@ func (c *OllamaClient) PullModel(req *PullModelRequest) (*PullModelResponse, err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	var pullModelResp PullModelResponse
@@ -126,12 +168,66 @@ type Message struct {
 	Content string `json:"content"`
 }
 
+// Options are the parameters of the model, the ones not set keep the values of the model.
+type Options struct {
+	// Temperature is the randomness of the answer, from 0 for the most likely one.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP keeps the most likely tokens up to this cumulative probability.
+	TopP *float64 `json:"top_p,omitempty"`
+	// NumCtx is the size of the context window in tokens.
+	NumCtx int `json:"num_ctx,omitempty"`
+	// NumPredict is the maximum number of tokens of the answer.
+	NumPredict int `json:"num_predict,omitempty"`
+	// Seed makes the answers reproducible for the same prompt.
+	Seed *int `json:"seed,omitempty"`
+	// Stop are the sequences that end the answer.
+	Stop []string `json:"stop,omitempty"`
+	// RepeatPenalty penalizes the repeated tokens, 1 disables it.
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	// Extra are the other options of the Ollama, like mirostat or num_gpu, sent with the ones above
+	// which take precedence.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the options with the extra ones.
+func (o Options) MarshalJSON() ([]byte, error) {
+	// The alias has the fields of the options without their MarshalJSON.
+	type options Options
+
+	data, err := json.Marshal(options(o))
+	if err != nil || len(o.Extra) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]interface{}, len(o.Extra))
+	for name, value := range o.Extra {
+		merged[name] = value
+	}
+
+	var known map[string]interface{}
+	if err := json.Unmarshal(data, &known); err != nil {
+		return nil, err
+	}
+
+	for name, value := range known {
+		merged[name] = value
+	}
+
+	return json.Marshal(merged)
+}
+
 // ChatRequest is a request to chat with the Ollama.
 type ChatRequest struct {
 	// Model is the model of the Ollama.
 	Model string `json:"model"`
 	// Messages is the messages of the Ollama.
 	Messages []Message `json:"messages"`
+	// Format is the format of the answer, json or a JSON schema.
+	Format string `json:"format,omitempty"`
+	// KeepAlive is how long the model stays in memory after the request, like 5m.
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// Options are the parameters of the model, like temperature.
+	Options *Options `json:"options,omitempty"`
 	// Stream is the stream of the model.
 	Stream bool `json:"stream"`
 }
@@ -144,19 +240,17 @@ type ChatResponse struct {
 	Message Message `json:"message"`
 	// Done is the done of the Ollama.
 	Done bool `json:"done"`
+	// PromptEvalCount is the number of tokens of the prompt, sent when done.
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	// EvalCount is the number of tokens of the answer, sent when done.
+	EvalCount int `json:"eval_count,omitempty"`
 }
 
 // Chat chats with the Ollama.
-func (c *OllamaClient) Chat(req *ChatRequest) (*ChatResponse, error) {
-	reqJSON, err := json.Marshal(req)
-
-	if err != nil {
-		return nil, err
-	}
-
+func (c *OllamaClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	req.Stream = false // We don't support streaming yet.
 
-	resp, err := c.HTTPClient.Post(c.Endpoint+"/api/chat", "application/json", bytes.NewBuffer(reqJSON))
+	resp, err := c.post(ctx, "/api/chat", req)
 
 	if err != nil {
 		return nil, err
@@ -165,7 +259,7 @@ func (c *OllamaClient) Chat(req *ChatRequest) (*ChatResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, statusError(resp)
 	}
 
 	var chatResp ChatResponse
@@ -187,56 +281,88 @@ type ChunkResponse struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
 	} `json:"message"`
-	Done bool `json:"done"`
+	Done            bool `json:"done"`
+	PromptEvalCount int  `json:"prompt_eval_count,omitempty"`
+	EvalCount       int  `json:"eval_count,omitempty"`
 }
 
-// ChatStream
-func (c *OllamaClient) ChatStream(req *ChatRequest) (<-chan ChunkResponse, <-chan error, error) {
+// ChatStream chats with the Ollama, streaming the answer. The chunks are sent on the first channel, which
+// is closed when the answer is done or fails. The error, if any, is sent on the second channel before.
+// When the context is canceled, the answer stops with the error of the context and the body is closed.
+func (c *OllamaClient) ChatStream(ctx context.Context, req *ChatRequest) (<-chan ChunkResponse, <-chan error, error) {
 	req.Stream = true // Force streaming
 
-	reqJSON, err := json.Marshal(req)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	resp, err := c.HTTPClient.Post(c.Endpoint+"/api/chat", "application/json", bytes.NewBuffer(reqJSON))
+	resp, err := c.post(ctx, "/api/chat", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		return nil, nil, statusError(resp)
 	}
 
 	messageChan := make(chan ChunkResponse, 10)
 	errorChan := make(chan error, 1)
 
+	// Closing the body stops the decoder blocked on a stalled Ollama.
+	stop := context.AfterFunc(ctx, func() {
+		resp.Body.Close()
+	})
+
 	go func() {
+		defer close(messageChan)
 		defer resp.Body.Close()
+		defer stop()
+
 		decoder := json.NewDecoder(resp.Body)
 		for {
 			var chunk ChunkResponse
-			if err := decoder.Decode(&chunk); err != nil {
-				if err == io.EOF {
-					close(messageChan)
-					break
-				} else {
-					errorChan <- err
-					close(messageChan)
-					close(errorChan)
-					return
-				}
+
+			err := decoder.Decode(&chunk)
+			if ctx.Err() != nil {
+				errorChan <- ctx.Err()
+				return
 			}
 
-			messageChan <- chunk
+			if err == io.EOF {
+				errorChan <- fmt.Errorf("the answer ended before it was done")
+				return
+			}
+
+			if err != nil {
+				errorChan <- err
+				return
+			}
+
+			select {
+			case messageChan <- chunk:
+			case <-ctx.Done():
+				errorChan <- ctx.Err()
+				return
+			}
 
 			if chunk.Done {
-				close(messageChan)
-				break
+				return
 			}
 		}
 	}()
 
 	return messageChan, errorChan, nil
 }
+
+// post sends a request with a JSON body.
+func (c *OllamaClient) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	reqJSON, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint+path, bytes.NewBuffer(reqJSON))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.HTTPClient.Do(req)
+}
@@ -0,0 +1,779 @@
+// Package ollamatest provides a fake Ollama for the tests of the Ollama client and of the assistants.
+package ollamatest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pishia-IA/core/thirdparty/ollama"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// DefaultVersion is the version returned by /api/version.
+	DefaultVersion = "0.5.0"
+	// layerSize is the size in bytes of the single layer of the models.
+	layerSize = 1000
+	// layerStep is the number of bytes of a layer downloaded or uploaded between two statuses.
+	layerStep = 250
+	// embeddingSize is the number of dimensions of the embeddings.
+	embeddingSize = 8
+)
+
+// ReplyFunc returns the answer of the model to the messages of a chat, or to the prompt of a completion
+// given as a user message.
+type ReplyFunc func(model string, messages []ollama.Message) string
+
+// Request is a request received by the Server.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request, like /api/chat.
+	Path string
+	// Body is the body of the request.
+	Body []byte
+}
+
+// Server is a fake Ollama, it serves the Ollama API and its OpenAI-compatible API from memory. The
+// answers are given by a ReplyFunc, EchoReply by default, and are streamed word by word.
+type Server struct {
+	*httptest.Server
+
+	// mu protects the fields below.
+	mu sync.Mutex
+	// version is the version returned by /api/version.
+	version string
+	// models are the local models, by name.
+	models map[string]ollama.Model
+	// running are the names of the models loaded in memory.
+	running map[string]bool
+	// partial are the bytes already downloaded of the models whose pull was interrupted.
+	partial map[string]int64
+	// interruptions is the number of the next pulls interrupted in the middle of the download.
+	interruptions int
+	// reply answers the chats and the completions.
+	reply ReplyFunc
+	// requests are the requests received, in order.
+	requests []Request
+}
+
+// NewServer starts a fake Ollama with the models. It must be closed with Close.
+func NewServer(models ...string) *Server {
+	s := &Server{
+		version: DefaultVersion,
+		models:  make(map[string]ollama.Model),
+		running: make(map[string]bool),
+		partial: make(map[string]int64),
+		reply:   EchoReply,
+	}
+
+	for _, name := range models {
+		s.AddModel(name)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/version", s.handleVersion)
+	mux.HandleFunc("GET /api/tags", s.handleTags)
+	mux.HandleFunc("GET /api/ps", s.handlePs)
+	mux.HandleFunc("POST /api/show", s.handleShow)
+	mux.HandleFunc("POST /api/pull", s.handlePull)
+	mux.HandleFunc("POST /api/push", s.handlePush)
+	mux.HandleFunc("POST /api/create", s.handleCreate)
+	mux.HandleFunc("POST /api/copy", s.handleCopy)
+	mux.HandleFunc("DELETE /api/delete", s.handleDelete)
+	mux.HandleFunc("POST /api/chat", s.handleChat)
+	mux.HandleFunc("POST /api/generate", s.handleGenerate)
+	mux.HandleFunc("POST /api/embed", s.handleEmbed)
+	mux.HandleFunc("GET /v1/models", s.handleOpenAIModels)
+	mux.HandleFunc("POST /v1/chat/completions", s.handleOpenAIChat)
+
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// EchoReply answers with the model and the last message, like "[model] echo: hello".
+func EchoReply(model string, messages []ollama.Message) string {
+	last := ""
+	if len(messages) > 0 {
+		last = messages[len(messages)-1].Content
+	}
+
+	return fmt.Sprintf("[%s] echo: %s", model, last)
+}
+
+// AddModel adds a local model.
+func (s *Server) AddModel(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.models[name] = ollama.Model{
+		Name:       name,
+		ModifiedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Size:       layerSize,
+		Digest:     digest(name),
+		Details: ollama.ModelDetails{
+			Format:            "gguf",
+			Family:            "llama",
+			ParameterSize:     "7B",
+			QuantizationLevel: "Q4_0",
+		},
+	}
+}
+
+// HasModel checks if the model is a local model.
+func (s *Server) HasModel(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.models[name]
+	return ok
+}
+
+// SetReply sets the function answering the chats and the completions.
+func (s *Server) SetReply(reply ReplyFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reply = reply
+}
+
+// SetVersion sets the version returned by /api/version.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// InterruptPulls interrupts the next n pulls in the middle of the download, the next pull of the model
+// resumes where it stopped.
+func (s *Server) InterruptPulls(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interruptions = n
+}
+
+// Requests returns the requests received, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// record keeps the requests before handling them.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Body: body})
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleVersion answers /api/version.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, ollama.VersionResponse{Version: s.version})
+}
+
+// handleTags answers /api/tags with the local models, sorted by name.
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	models := make([]ollama.Model, 0, len(s.models))
+	for _, model := range s.models {
+		models = append(models, model)
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, ollama.ListModelsResponse{Models: models})
+}
+
+// handlePs answers /api/ps with the models used since the server started.
+func (s *Server) handlePs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	models := make([]ollama.RunningModel, 0, len(s.running))
+	for name := range s.running {
+		model := s.models[name]
+		models = append(models, ollama.RunningModel{
+			Name:      name,
+			Size:      model.Size,
+			SizeVRAM:  model.Size,
+			Digest:    model.Digest,
+			Details:   model.Details,
+			ExpiresAt: time.Now().Add(5 * time.Minute),
+		})
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Name < models[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, ollama.ListRunningModelsResponse{Models: models})
+}
+
+// handleShow answers /api/show.
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	var req ollama.ShowModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	model, ok := s.model(w, req.Name)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ollama.ShowModelResponse{
+		ModelFile:  "FROM " + model.Name,
+		Parameters: "stop \"<|im_end|>\"",
+		Template:   "{{ .Prompt }}",
+		Details:    model.Details,
+	})
+}
+
+// handlePull answers /api/pull, the model is added once downloaded.
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	var req ollama.PullModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	interrupted := s.interruptions > 0
+	if interrupted {
+		s.interruptions--
+	}
+	start := s.partial[req.Name]
+	s.mu.Unlock()
+
+	progress := newProgressWriter(w, req.Stream)
+	progress.Send(ollama.ProgressResponse{Status: "pulling manifest"})
+
+	for completed := start; completed <= layerSize; completed += layerStep {
+		progress.Send(ollama.ProgressResponse{
+			Status:    "pulling " + digest(req.Name)[7:19],
+			Digest:    digest(req.Name),
+			Total:     layerSize,
+			Completed: completed,
+		})
+
+		if interrupted && completed >= layerSize/2 {
+			s.mu.Lock()
+			s.partial[req.Name] = completed
+			s.mu.Unlock()
+
+			// The connection is closed without the success status.
+			progress.Abort()
+			return
+		}
+	}
+
+	s.AddModel(req.Name)
+
+	s.mu.Lock()
+	delete(s.partial, req.Name)
+	s.mu.Unlock()
+
+	progress.Send(ollama.ProgressResponse{Status: "verifying sha256 digest"})
+	progress.Send(ollama.ProgressResponse{Status: "writing manifest"})
+	progress.Done()
+}
+
+// handlePush answers /api/push.
+func (s *Server) handlePush(w http.ResponseWriter, r *http.Request) {
+	var req ollama.PushModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	model, ok := s.model(w, req.Name)
+	if !ok {
+		return
+	}
+
+	progress := newProgressWriter(w, req.Stream)
+	progress.Send(ollama.ProgressResponse{Status: "retrieving manifest"})
+
+	for completed := int64(0); completed <= layerSize; completed += layerStep {
+		progress.Send(ollama.ProgressResponse{
+			Status:    "pushing " + model.Digest[7:19],
+			Digest:    model.Digest,
+			Total:     layerSize,
+			Completed: completed,
+		})
+	}
+
+	progress.Send(ollama.ProgressResponse{Status: "pushing manifest"})
+	progress.Done()
+}
+
+// handleCreate answers /api/create, the model is created from the model of from or of the FROM line of
+// the Modelfile.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req ollama.CreateModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	from := req.From
+	for _, line := range strings.Split(req.Modelfile, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && strings.EqualFold(fields[0], "FROM") {
+			from = fields[1]
+		}
+	}
+
+	if req.Name == "" || from == "" {
+		writeError(w, http.StatusBadRequest, "name and from, or a Modelfile with FROM, are required")
+		return
+	}
+
+	if _, ok := s.model(w, from); !ok {
+		return
+	}
+
+	progress := newProgressWriter(w, req.Stream)
+	progress.Send(ollama.ProgressResponse{Status: "using existing layer " + digest(from)})
+	progress.Send(ollama.ProgressResponse{Status: "writing manifest"})
+
+	s.AddModel(req.Name)
+	progress.Done()
+}
+
+// handleCopy answers /api/copy.
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	var req ollama.CopyModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.model(w, req.Source); !ok {
+		return
+	}
+
+	s.AddModel(req.Destination)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDelete answers /api/delete.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var req ollama.DeleteModelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.model(w, req.Name); !ok {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.models, req.Name)
+	delete(s.running, req.Name)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleChat answers /api/chat.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req ollama.ChatRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.model(w, req.Model); !ok {
+		return
+	}
+
+	words := s.answer(req.Model, req.Messages)
+	promptTokens := countTokens(req.Messages)
+
+	if !req.Stream {
+		writeJSON(w, http.StatusOK, ollama.ChatResponse{
+			Model:           req.Model,
+			Message:         ollama.Message{Role: "assistant", Content: strings.Join(words, "")},
+			Done:            true,
+			PromptEvalCount: promptTokens,
+			EvalCount:       len(words),
+		})
+		return
+	}
+
+	stream := newNDJSONWriter(w)
+	for _, word := range words {
+		chunk := ollama.ChunkResponse{Model: req.Model, CreatedAt: time.Now().Format(time.RFC3339Nano)}
+		chunk.Message.Role, chunk.Message.Content = "assistant", word
+		stream.Send(chunk)
+	}
+
+	done := ollama.ChunkResponse{Model: req.Model, Done: true, PromptEvalCount: promptTokens, EvalCount: len(words)}
+	done.Message.Role = "assistant"
+	stream.Send(done)
+}
+
+// handleGenerate answers /api/generate. The context returned is the one of the request followed by a
+// token per word of the prompt and of the answer.
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req ollama.GenerateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.model(w, req.Model); !ok {
+		return
+	}
+
+	for i, image := range req.Images {
+		if _, err := base64.StdEncoding.DecodeString(image); err != nil {
+			writeError(w, http.StatusBadRequest, "image %d is not base64 encoded: %v", i, err)
+			return
+		}
+	}
+
+	messages := make([]ollama.Message, 0, 2)
+	if req.System != "" && !req.Raw {
+		messages = append(messages, ollama.Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, ollama.Message{Role: "user", Content: req.Prompt})
+
+	words := s.answer(req.Model, messages)
+	promptTokens := countTokens(messages)
+
+	context := append([]int(nil), req.Context...)
+	for i := 0; i < promptTokens+len(words); i++ {
+		context = append(context, len(context)+1)
+	}
+
+	done := ollama.GenerateResponse{
+		Model:           req.Model,
+		CreatedAt:       time.Now(),
+		Done:            true,
+		DoneReason:      "stop",
+		Context:         context,
+		PromptEvalCount: promptTokens,
+		EvalCount:       len(words),
+	}
+
+	if !req.Stream {
+		done.Response = strings.Join(words, "")
+		writeJSON(w, http.StatusOK, done)
+		return
+	}
+
+	stream := newNDJSONWriter(w)
+	for _, word := range words {
+		stream.Send(ollama.GenerateResponse{Model: req.Model, CreatedAt: time.Now(), Response: word})
+	}
+	stream.Send(done)
+}
+
+// handleEmbed answers /api/embed with embeddings computed from a hash of the texts, the same text
+// always has the same embedding.
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if _, ok := s.model(w, req.Model); !ok {
+		return
+	}
+
+	// The input is a text or a list of texts.
+	var inputs []string
+	if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(req.Input, &input); err != nil {
+			writeError(w, http.StatusBadRequest, "input must be a string or a list of strings")
+			return
+		}
+		inputs = []string{input}
+	}
+
+	embeddings := make([][]float32, 0, len(inputs))
+	tokens := 0
+	for _, input := range inputs {
+		embeddings = append(embeddings, Embedding(input))
+		tokens += len(strings.Fields(input))
+	}
+
+	s.load(req.Model)
+	writeJSON(w, http.StatusOK, ollama.EmbedResponse{Model: req.Model, Embeddings: embeddings, PromptEvalCount: tokens})
+}
+
+// handleOpenAIModels answers /v1/models of the OpenAI-compatible API.
+func (s *Server) handleOpenAIModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	models := make([]openai.Model, 0, len(s.models))
+	for _, model := range s.models {
+		models = append(models, openai.Model{ID: model.Name, Object: "model", CreatedAt: model.ModifiedAt.Unix(), OwnedBy: "library"})
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+// handleOpenAIChat answers /v1/chat/completions of the OpenAI-compatible API.
+func (s *Server) handleOpenAIChat(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if !s.HasModel(req.Model) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]string{"message": fmt.Sprintf("model %q not found", req.Model), "type": "invalid_request_error"},
+		})
+		return
+	}
+
+	messages := make([]ollama.Message, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		messages = append(messages, ollama.Message{Role: message.Role, Content: message.Content})
+	}
+
+	words := s.answer(req.Model, messages)
+	usage := openai.Usage{PromptTokens: countTokens(messages), CompletionTokens: len(words)}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	id := "chatcmpl-ollamatest"
+
+	if !req.Stream {
+		writeJSON(w, http.StatusOK, openai.ChatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: time.Now().Unix(),
+			Model:   req.Model,
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: "assistant", Content: strings.Join(words, "")},
+				FinishReason: openai.FinishReasonStop,
+			}},
+			Usage: usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk openai.ChatCompletionStreamResponse) {
+		chunk.ID, chunk.Object, chunk.Created, chunk.Model = id, "chat.completion.chunk", time.Now().Unix(), req.Model
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for i, word := range words {
+		delta := openai.ChatCompletionStreamChoiceDelta{Content: word}
+		if i == 0 {
+			delta.Role = "assistant"
+		}
+		send(openai.ChatCompletionStreamResponse{Choices: []openai.ChatCompletionStreamChoice{{Delta: delta}}})
+	}
+
+	send(openai.ChatCompletionStreamResponse{Choices: []openai.ChatCompletionStreamChoice{{FinishReason: openai.FinishReasonStop}}})
+
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		send(openai.ChatCompletionStreamResponse{Choices: []openai.ChatCompletionStreamChoice{}, Usage: &usage})
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// model returns a local model, or answers with a not found error.
+func (s *Server) model(w http.ResponseWriter, name string) (ollama.Model, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	model, ok := s.models[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "model %q not found, try pulling it first", name)
+	}
+
+	return model, ok
+}
+
+// answer returns the answer of the model to the messages, split in words keeping the spaces, and loads
+// the model in memory.
+func (s *Server) answer(model string, messages []ollama.Message) []string {
+	s.mu.Lock()
+	reply := s.reply
+	s.mu.Unlock()
+
+	s.load(model)
+
+	words := strings.SplitAfter(reply(model, messages), " ")
+	if len(words) > 0 && words[len(words)-1] == "" {
+		words = words[:len(words)-1]
+	}
+
+	return words
+}
+
+// load marks the model as loaded in memory.
+func (s *Server) load(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[model] = true
+}
+
+// Embedding returns the embedding the Server computes for the text.
+func Embedding(text string) []float32 {
+	embedding := make([]float32, embeddingSize)
+	norm := 0.0
+
+	for i := range embedding {
+		hash := fnv.New32a()
+		fmt.Fprintf(hash, "%d:%s", i, text)
+
+		value := float64(hash.Sum32())/math.MaxUint32*2 - 1
+		embedding[i] = float32(value)
+		norm += value * value
+	}
+
+	for i := range embedding {
+		embedding[i] /= float32(math.Sqrt(norm))
+	}
+
+	return embedding
+}
+
+// countTokens counts the tokens of the messages, a token per word.
+func countTokens(messages []ollama.Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += len(strings.Fields(message.Content))
+	}
+
+	return tokens
+}
+
+// digest returns the digest of the layer of a model.
+func digest(name string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(name)))
+}
+
+// readJSON decodes the JSON body of the request into v, or answers with a bad request error.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the error like the Ollama does.
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// ndjsonWriter writes the values of a streamed response, a JSON value per line.
+type ndjsonWriter struct {
+	// w is the response.
+	w http.ResponseWriter
+	// encoder encodes the values on w.
+	encoder *json.Encoder
+}
+
+// newNDJSONWriter starts a streamed response.
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	return &ndjsonWriter{w: w, encoder: json.NewEncoder(w)}
+}
+
+// Send writes a value and flushes it.
+func (n *ndjsonWriter) Send(v interface{}) {
+	n.encoder.Encode(v)
+
+	if flusher, ok := n.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// progressWriter writes the statuses of a create, pull or push. When the request is not streamed, only
+// the last status is written.
+type progressWriter struct {
+	// w is the response.
+	w http.ResponseWriter
+	// stream is the writer of the statuses, nil when the request is not streamed.
+	stream *ndjsonWriter
+}
+
+// newProgressWriter starts the response of a create, pull or push.
+func newProgressWriter(w http.ResponseWriter, stream bool) *progressWriter {
+	progress := &progressWriter{w: w}
+	if stream {
+		progress.stream = newNDJSONWriter(w)
+	}
+
+	return progress
+}
+
+// Send writes a status, when the request is streamed.
+func (p *progressWriter) Send(status ollama.ProgressResponse) {
+	if p.stream != nil {
+		p.stream.Send(status)
+	}
+}
+
+// Done writes the success status.
+func (p *progressWriter) Done() {
+	if p.stream != nil {
+		p.stream.Send(ollama.ProgressResponse{Status: ollama.PullStatusSuccess})
+		return
+	}
+
+	writeJSON(p.w, http.StatusOK, ollama.ProgressResponse{Status: ollama.PullStatusSuccess})
+}
+
+// Abort closes the connection without the success status, like an Ollama losing its connection to the
+// registry.
+func (p *progressWriter) Abort() {
+	if p.stream == nil {
+		writeError(p.w, http.StatusBadGateway, "the download was interrupted")
+		return
+	}
+
+	panic(http.ErrAbortHandler)
+}